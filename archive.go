@@ -0,0 +1,198 @@
+package ember
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// The supported archive formats.
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+)
+
+// ArchiveFileLimit is the maximum size of a single file read from an archive.
+var ArchiveFileLimit int64 = 64 << 20 // 64 MiB
+
+// ArchiveSizeLimit is the maximum total size of all files read from an archive.
+var ArchiveSizeLimit int64 = 512 << 20 // 512 MiB
+
+// MustFilesFromArchive will call FilesFromArchive and panic on errors.
+func MustFilesFromArchive(r io.Reader, format, dir string) map[string]string {
+	// get files
+	files, err := FilesFromArchive(r, format, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return files
+}
+
+// FilesFromArchive will return a file map from the provided archive directory.
+// The format must be either Zip or TarGz. Entries that would escape the
+// archive root are rejected and the sizes are limited by ArchiveFileLimit and
+// ArchiveSizeLimit. If dir is empty, all files in the archive are returned.
+func FilesFromArchive(r io.Reader, format, dir string) (map[string]string, error) {
+	// trim dir
+	dir = strings.Trim(dir, "/")
+
+	// prepare collector
+	files := make(map[string]string)
+	var total int64
+	add := func(name string, rc io.Reader) error {
+		// clean name
+		name, err := cleanArchivePath(name)
+		if err != nil {
+			return err
+		}
+
+		// check dir
+		if dir != "" {
+			if !strings.HasPrefix(name, dir+"/") {
+				return nil
+			}
+			name = strings.TrimPrefix(name, dir+"/")
+		}
+
+		// read file
+		buf, err := io.ReadAll(io.LimitReader(rc, ArchiveFileLimit+1))
+		if err != nil {
+			return err
+		}
+
+		// check limits
+		if int64(len(buf)) > ArchiveFileLimit {
+			return fmt.Errorf("archive file too large: %s", name)
+		}
+		total += int64(len(buf))
+		if total > ArchiveSizeLimit {
+			return fmt.Errorf("archive too large")
+		}
+
+		// add file
+		files[name] = string(buf)
+
+		return nil
+	}
+
+	// read archive
+	var err error
+	switch format {
+	case Zip:
+		err = readZip(r, add)
+	case TarGz:
+		err = readTarGz(r, add)
+	default:
+		err = fmt.Errorf("unsupported archive format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ArchiveFormat will return the archive format for the provided file name or
+// an empty string if the format is not supported.
+func ArchiveFormat(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return Zip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz
+	default:
+		return ""
+	}
+}
+
+func readZip(r io.Reader, add func(string, io.Reader) error) error {
+	// read archive
+	buf, err := io.ReadAll(io.LimitReader(r, ArchiveSizeLimit+1))
+	if err != nil {
+		return err
+	} else if int64(len(buf)) > ArchiveSizeLimit {
+		return fmt.Errorf("archive too large")
+	}
+
+	// open archive
+	zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return err
+	}
+
+	// add files
+	for _, file := range zr.File {
+		// skip non-regular files
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		// open file
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+
+		// add file
+		err = add(file.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readTarGz(r io.Reader, add func(string, io.Reader) error) error {
+	// open gzip stream
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	// add files
+	tr := tar.NewReader(gr)
+	for {
+		// get next header
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// skip non-regular files
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// add file
+		err = add(header.Name, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func cleanArchivePath(name string) (string, error) {
+	// check name
+	if strings.Contains(name, `\`) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid archive path: %s", name)
+	}
+
+	// clean name
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid archive path: %s", name)
+	}
+
+	return cleaned, nil
+}
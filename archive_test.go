@@ -0,0 +1,117 @@
+package ember
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesFromArchive(t *testing.T) {
+	entries := map[string]string{
+		"./dist/index.html":       indexHTML,
+		"dist/assets/script.js":   scriptJS,
+		"other/assets/script.css": appCSS,
+	}
+
+	for _, format := range []string{Zip, TarGz} {
+		files, err := FilesFromArchive(buildArchive(format, entries), format, "dist")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"index.html":       indexHTML,
+			"assets/script.js": scriptJS,
+		}, files)
+
+		files, err = FilesFromArchive(buildArchive(format, entries), format, "")
+		assert.NoError(t, err)
+		assert.Len(t, files, 3)
+		assert.Equal(t, appCSS, files["other/assets/script.css"])
+
+		_, err = FilesFromArchive(buildArchive(format, map[string]string{
+			"dist/../../etc/passwd": "foo",
+		}), format, "dist")
+		assert.Error(t, err)
+
+		_, err = FilesFromArchive(buildArchive(format, map[string]string{
+			"/etc/passwd": "foo",
+		}), format, "")
+		assert.Error(t, err)
+	}
+
+	_, err := FilesFromArchive(bytes.NewReader(nil), "rar", "")
+	assert.Error(t, err)
+
+	assert.Equal(t, Zip, ArchiveFormat("dist.zip"))
+	assert.Equal(t, TarGz, ArchiveFormat("dist.tar.gz"))
+	assert.Equal(t, TarGz, ArchiveFormat("dist.tgz"))
+	assert.Equal(t, "", ArchiveFormat("dist"))
+}
+
+func TestFilesFromArchiveLimits(t *testing.T) {
+	limit := ArchiveFileLimit
+	ArchiveFileLimit = 4
+	defer func() {
+		ArchiveFileLimit = limit
+	}()
+
+	for _, format := range []string{Zip, TarGz} {
+		_, err := FilesFromArchive(buildArchive(format, map[string]string{
+			"index.html": indexHTML,
+		}), format, "")
+		assert.Error(t, err)
+	}
+}
+
+func buildArchive(format string, entries map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	switch format {
+	case Zip:
+		zw := zip.NewWriter(&buf)
+		for name, content := range entries {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name})
+			if err != nil {
+				panic(err)
+			}
+			_, err = w.Write([]byte(content))
+			if err != nil {
+				panic(err)
+			}
+		}
+		err := zw.Close()
+		if err != nil {
+			panic(err)
+		}
+	case TarGz:
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		for name, content := range entries {
+			err := tw.WriteHeader(&tar.Header{
+				Name:     name,
+				Typeflag: tar.TypeReg,
+				Mode:     0644,
+				Size:     int64(len(content)),
+			})
+			if err != nil {
+				panic(err)
+			}
+			_, err = tw.Write([]byte(content))
+			if err != nil {
+				panic(err)
+			}
+		}
+		err := tw.Close()
+		if err != nil {
+			panic(err)
+		}
+		err = gw.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return &buf
+}
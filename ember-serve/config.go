@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/256dpi/ember"
+	"github.com/256dpi/ember/fastboot"
+)
+
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	// parse string
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	// parse duration
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	*d = duration(dur)
+
+	return nil
+}
+
+type serverConfig struct {
+	Addr            string            `json:"addr"`
+	TLSCert         string            `json:"tlsCert"`
+	TLSKey          string            `json:"tlsKey"`
+	TLSSelfSigned   bool              `json:"tlsSelfSigned"`
+	Metrics         string            `json:"metrics"`
+	Log             bool              `json:"log"`
+	LogFormat       string            `json:"logFormat"`
+	ShutdownTimeout duration          `json:"shutdownTimeout"`
+	Watch           bool              `json:"watch"`
+	WatchInterval   duration          `json:"watchInterval"`
+	Proxies         map[string]string `json:"proxies"`
+	Apps            []appConfig       `json:"apps"`
+
+	collector *ember.Collector
+	logger    *slog.Logger
+}
+
+type appConfig struct {
+	Source        string                 `json:"source"`
+	ArchiveDir    string                 `json:"archiveDir"`
+	Name          string                 `json:"name"`
+	Prefix        string                 `json:"prefix"`
+	Set           map[string]interface{} `json:"set"`
+	Head          []string               `json:"head"`
+	HeadFiles     []string               `json:"headFiles"`
+	InlineScripts []string               `json:"inlineScripts"`
+	Headers       map[string]string      `json:"headers"`
+	FastBoot      *fastbootConfig        `json:"fastboot"`
+}
+
+type fastbootConfig struct {
+	Timeout      duration `json:"timeout"`
+	Cache        duration `json:"cache"`
+	CacheStale   duration `json:"cacheStale"`
+	CacheSize    int64    `json:"cacheSize"`
+	CacheDir     string   `json:"cacheDir"`
+	WarmURLs     []string `json:"warmURLs"`
+	WarmSitemap  string   `json:"warmSitemap"`
+	PurgePath    string   `json:"purgePath"`
+	PurgeToken   string   `json:"purgeToken"`
+	Isolated     bool     `json:"isolated"`
+	PoolSize     int      `json:"poolSize"`
+	AllowHeaders []string `json:"allowHeaders"`
+	DenyHeaders  []string `json:"denyHeaders"`
+	Origin       string   `json:"origin"`
+	Headed       bool     `json:"headed"`
+}
+
+func loadConfig(file string) (*serverConfig, error) {
+	// read file
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// parse config
+	var config serverConfig
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// check apps
+	if len(config.Apps) == 0 {
+		return nil, fmt.Errorf("missing apps in config")
+	}
+
+	// check prefixes
+	prefixes := map[string]bool{}
+	for _, app := range config.Apps {
+		prefix := strings.Trim(app.Prefix, "/")
+		if prefixes[prefix] {
+			return nil, fmt.Errorf("duplicate app prefix %q in config", "/"+prefix)
+		}
+		prefixes[prefix] = true
+
+		// check purge token
+		if app.FastBoot != nil && app.FastBoot.PurgePath != "" && app.FastBoot.PurgeToken == "" {
+			return nil, fmt.Errorf("missing purge token for purge path %q in config", app.FastBoot.PurgePath)
+		}
+	}
+
+	// apply defaults
+	config.defaults()
+
+	return &config, nil
+}
+
+func (c *serverConfig) secure() bool {
+	return c.TLSSelfSigned || c.TLSCert != ""
+}
+
+func (c *serverConfig) defaults() {
+	// ensure defaults
+	if c.Addr == "" {
+		c.Addr = ":8000"
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = duration(10 * time.Second)
+	}
+	if c.WatchInterval == 0 {
+		c.WatchInterval = duration(500 * time.Millisecond)
+	}
+}
+
+func (c *serverConfig) build() (http.Handler, []*fastboot.Handler, error) {
+	// ensure defaults
+	c.defaults()
+
+	// prepare metrics (once, as they are published globally)
+	var metrics ember.Metrics
+	if c.Metrics != "" && c.collector == nil {
+		c.collector = ember.NewCollector()
+		c.collector.Publish("ember")
+	}
+	if c.collector != nil {
+		metrics = c.collector
+	}
+
+	// prepare logger
+	if c.Log && c.logger == nil {
+		var err error
+		c.logger, err = newLogger(c.LogFormat)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// prepare mux
+	mux := http.NewServeMux()
+	if c.collector != nil {
+		mux.Handle(c.Metrics, c.collector)
+	}
+
+	// mount apps
+	var renderers []*fastboot.Handler
+	for _, config := range c.Apps {
+		handler, renderer, err := c.buildApp(config, metrics)
+		if renderer != nil {
+			renderers = append(renderers, renderer)
+		}
+		if err != nil {
+			return nil, renderers, err
+		}
+
+		// mount handler
+		prefix := strings.Trim(config.Prefix, "/")
+		if prefix != "" {
+			mux.Handle("/"+prefix+"/", http.StripPrefix("/"+prefix, handler))
+		} else {
+			mux.Handle("/", handler)
+		}
+	}
+
+	// prepare proxy rules
+	var rules []string
+	for prefix, target := range c.Proxies {
+		rules = append(rules, prefix+"="+target)
+	}
+	sort.Strings(rules)
+
+	// handle proxies
+	handler, err := proxyHandler(rules, mux)
+	if err != nil {
+		return nil, renderers, err
+	}
+
+	return handler, renderers, nil
+}
+
+func (c *serverConfig) buildApp(config appConfig, metrics ember.Metrics) (http.Handler, *fastboot.Handler, error) {
+	// ensure name
+	if config.Name == "" {
+		config.Name = "example"
+	}
+
+	// load files
+	files, err := loadFiles(config.Source, config.ArchiveDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create app
+	app, err := ember.Create(config.Name, files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// customize app
+	err = customize(app, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// inject live reload client when watching development builds
+	if c.Watch && app.Get("environment") == "development" {
+		app.AddInlineScript(liveReloadScript)
+	}
+
+	// set metrics
+	if metrics != nil {
+		app.WithMetrics(metrics)
+	}
+
+	// set logger (FastBoot handlers log on their own)
+	if c.logger != nil && config.FastBoot == nil {
+		app.WithLogger(c.logger)
+	}
+
+	// prepare handler
+	var handler http.Handler = app
+
+	// handle fastboot
+	var renderer *fastboot.Handler
+	if config.FastBoot != nil {
+		// determine origin
+		origin := config.FastBoot.Origin
+		if origin == "" {
+			origin = localOrigin(c.Addr)
+		}
+		if c.secure() {
+			origin = secureOrigin(origin)
+		}
+
+		// prepare cache store
+		var store fastboot.Cache
+		if config.FastBoot.CacheDir != "" {
+			store, err = fastboot.NewFileCache(config.FastBoot.CacheDir)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else if config.FastBoot.CacheSize > 0 {
+			store = fastboot.NewMemoryCache(config.FastBoot.CacheSize)
+		}
+
+		// create handler
+		renderer, err = fastboot.Handle(fastboot.Options{
+			App:          app,
+			Origin:       origin,
+			Timeout:      time.Duration(config.FastBoot.Timeout),
+			Cache:        time.Duration(config.FastBoot.Cache),
+			CacheStore:   store,
+			CacheStale:   time.Duration(config.FastBoot.CacheStale),
+			WarmURLs:     config.FastBoot.WarmURLs,
+			WarmSitemap:  config.FastBoot.WarmSitemap,
+			PurgePath:    config.FastBoot.PurgePath,
+			PurgeToken:   config.FastBoot.PurgeToken,
+			Isolated:     config.FastBoot.Isolated,
+			PoolSize:     config.FastBoot.PoolSize,
+			AllowHeaders: config.FastBoot.AllowHeaders,
+			DenyHeaders:  config.FastBoot.DenyHeaders,
+			Headed:       config.FastBoot.Headed,
+			Metrics:      metrics,
+			Logger:       c.logger,
+			OnError: func(err error) {
+				if c.logger == nil {
+					_, _ = fmt.Println("==> Error: " + err.Error())
+				}
+			},
+			OnWarm: func(url string, done, total int, err error) {
+				switch {
+				case c.logger != nil && err != nil:
+					c.logger.Warn("warm", "url", url, "done", done, "total", total, "error", err.Error())
+				case c.logger != nil:
+					c.logger.Info("warm", "url", url, "done", done, "total", total)
+				case err != nil:
+					_, _ = fmt.Printf("==> Warm failed (%d/%d): %s: %s\n", done, total, url, err.Error())
+				default:
+					_, _ = fmt.Printf("==> Warmed (%d/%d): %s\n", done, total, url)
+				}
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		handler = renderer
+	}
+
+	// set headers
+	if len(config.Headers) > 0 {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for key, value := range config.Headers {
+				w.Header().Set(key, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return handler, renderer, nil
+}
+
+func localOrigin(addr string) string {
+	// get port
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil || port == "" {
+		return "http://localhost"
+	}
+
+	return "http://localhost:" + port
+}
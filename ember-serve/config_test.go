@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	for _, item := range []struct {
+		name   string
+		data   string
+		config *serverConfig
+		err    string
+	}{
+		{
+			name: "minimal",
+			data: `{"apps":[{"source":"dist"}]}`,
+			config: &serverConfig{
+				Addr:            ":8000",
+				ShutdownTimeout: duration(10 * time.Second),
+				WatchInterval:   duration(500 * time.Millisecond),
+				Apps:            []appConfig{{Source: "dist"}},
+			},
+		},
+		{
+			name: "durations",
+			data: `{"shutdownTimeout":"30s","watchInterval":"250ms","apps":[{"source":"dist","fastboot":{"timeout":"2s","cache":"1m","cacheStale":"1h"}}]}`,
+			config: &serverConfig{
+				Addr:            ":8000",
+				ShutdownTimeout: duration(30 * time.Second),
+				WatchInterval:   duration(250 * time.Millisecond),
+				Apps: []appConfig{{
+					Source: "dist",
+					FastBoot: &fastbootConfig{
+						Timeout:    duration(2 * time.Second),
+						Cache:      duration(time.Minute),
+						CacheStale: duration(time.Hour),
+					},
+				}},
+			},
+		},
+		{
+			name: "multiple apps",
+			data: `{"addr":":9000","apps":[{"source":"a"},{"source":"b","prefix":"/admin/"}]}`,
+			config: &serverConfig{
+				Addr:            ":9000",
+				ShutdownTimeout: duration(10 * time.Second),
+				WatchInterval:   duration(500 * time.Millisecond),
+				Apps:            []appConfig{{Source: "a"}, {Source: "b", Prefix: "/admin/"}},
+			},
+		},
+		{
+			name: "invalid duration",
+			data: `{"shutdownTimeout":"soon","apps":[{"source":"dist"}]}`,
+			err:  `failed to parse config: time: invalid duration "soon"`,
+		},
+		{
+			name: "numeric duration",
+			data: `{"shutdownTimeout":30,"apps":[{"source":"dist"}]}`,
+			err:  "failed to parse config: json: cannot unmarshal number into Go value of type string",
+		},
+		{
+			name: "missing apps",
+			data: `{"addr":":8000"}`,
+			err:  "missing apps in config",
+		},
+		{
+			name: "empty apps",
+			data: `{"apps":[]}`,
+			err:  "missing apps in config",
+		},
+		{
+			name: "duplicate root apps",
+			data: `{"apps":[{"source":"a"},{"source":"b"}]}`,
+			err:  `duplicate app prefix "/" in config`,
+		},
+		{
+			name: "missing purge token",
+			data: `{"apps":[{"source":"a","fastboot":{"purgePath":"/_purge"}}]}`,
+			err:  `missing purge token for purge path "/_purge" in config`,
+		},
+		{
+			name: "duplicate prefixes",
+			data: `{"apps":[{"source":"a","prefix":"admin"},{"source":"b","prefix":"/admin/"}]}`,
+			err:  `duplicate app prefix "/admin" in config`,
+		},
+	} {
+		file := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(file, []byte(item.data), 0644)
+		assert.NoError(t, err)
+
+		config, err := loadConfig(file)
+		if item.err != "" {
+			assert.EqualError(t, err, item.err, item.name)
+			continue
+		}
+		assert.NoError(t, err, item.name)
+		assert.Equal(t, item.config, config, item.name)
+	}
+
+	_, err := loadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestLocalOrigin(t *testing.T) {
+	for addr, origin := range map[string]string{
+		":8000":          "http://localhost:8000",
+		"127.0.0.1:8000": "http://localhost:8000",
+		"[::1]:9000":     "http://localhost:9000",
+		"localhost":      "http://localhost",
+	} {
+		assert.Equal(t, origin, localOrigin(addr), addr)
+	}
+}
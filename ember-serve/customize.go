@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/256dpi/ember"
+)
+
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func parseSetting(setting string) (string, interface{}, error) {
+	// split setting
+	key, raw, ok := strings.Cut(setting, "=")
+	if !ok || key == "" {
+		return "", nil, fmt.Errorf("invalid setting: %s", setting)
+	}
+
+	// parse value as JSON and fall back to a string
+	var value interface{}
+	err := json.Unmarshal([]byte(raw), &value)
+	if err != nil {
+		value = raw
+	}
+
+	return key, value, nil
+}
+
+func setPath(app *ember.App, key string, value interface{}) {
+	// split key
+	segments := strings.Split(key, ".")
+
+	// set top-level setting
+	if len(segments) == 1 {
+		app.Set(key, value)
+		return
+	}
+
+	// copy root setting
+	root, _ := deepCopy(app.Get(segments[0])).(map[string]interface{})
+	if root == nil {
+		root = map[string]interface{}{}
+	}
+
+	// walk and create nested settings
+	current := root
+	for _, segment := range segments[1 : len(segments)-1] {
+		next, ok := current[segment].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[segment] = next
+		}
+		current = next
+	}
+
+	// set value
+	current[segments[len(segments)-1]] = value
+	app.Set(segments[0], root)
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			out[key] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func customize(app *ember.App, config appConfig) error {
+	// sort keys to apply parent settings first
+	var keys []string
+	for key := range config.Set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// apply settings
+	for _, key := range keys {
+		setPath(app, key, config.Set[key])
+	}
+
+	// append head tags
+	for _, tag := range config.Head {
+		app.AppendHead(tag)
+	}
+
+	// append head files
+	for _, file := range config.HeadFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		app.AppendHead(string(data))
+	}
+
+	// add inline scripts
+	for _, file := range config.InlineScripts {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		app.AddInlineScript(string(data))
+	}
+
+	// apply prefix
+	if config.Prefix != "" {
+		app.Prefix(config.Prefix, nil, true)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember"
+)
+
+func TestParseSetting(t *testing.T) {
+	for _, item := range []struct {
+		setting string
+		key     string
+		value   interface{}
+		err     string
+	}{
+		{setting: "foo=bar", key: "foo", value: "bar"},
+		{setting: `foo="bar"`, key: "foo", value: "bar"},
+		{setting: "foo=42", key: "foo", value: 42.0},
+		{setting: "foo=true", key: "foo", value: true},
+		{setting: "foo=null", key: "foo", value: nil},
+		{setting: `foo={"a":1}`, key: "foo", value: map[string]interface{}{"a": 1.0}},
+		{setting: "foo=[1,2", key: "foo", value: "[1,2"},
+		{setting: "foo=", key: "foo", value: ""},
+		{setting: "foo.bar=a=b", key: "foo.bar", value: "a=b"},
+		{setting: "foo", err: "invalid setting: foo"},
+		{setting: "=bar", err: "invalid setting: =bar"},
+	} {
+		key, value, err := parseSetting(item.setting)
+		if item.err != "" {
+			assert.EqualError(t, err, item.err, item.setting)
+			continue
+		}
+		assert.NoError(t, err, item.setting)
+		assert.Equal(t, item.key, key, item.setting)
+		assert.Equal(t, item.value, value, item.setting)
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	for _, item := range []struct {
+		key    string
+		value  interface{}
+		result map[string]interface{}
+	}{
+		{
+			key:   "foo",
+			value: "bar",
+			result: map[string]interface{}{
+				"foo": "bar",
+				"APP": map[string]interface{}{"name": "app"},
+			},
+		},
+		{
+			key:   "APP.debug",
+			value: true,
+			result: map[string]interface{}{
+				"APP": map[string]interface{}{"name": "app", "debug": true},
+			},
+		},
+		{
+			key:   "APP.name",
+			value: "foo",
+			result: map[string]interface{}{
+				"APP": map[string]interface{}{"name": "foo"},
+			},
+		},
+		{
+			key:   "APP.nested.deep.value",
+			value: 1.0,
+			result: map[string]interface{}{
+				"APP": map[string]interface{}{
+					"name": "app",
+					"nested": map[string]interface{}{
+						"deep": map[string]interface{}{"value": 1.0},
+					},
+				},
+			},
+		},
+		{
+			key:   "API.host",
+			value: "example.org",
+			result: map[string]interface{}{
+				"APP": map[string]interface{}{"name": "app"},
+				"API": map[string]interface{}{"host": "example.org"},
+			},
+		},
+	} {
+		base := ember.MustCreate("app", map[string]string{
+			"index.html": `<meta name="app/config/environment" content="%7B%22APP%22%3A%7B%22name%22%3A%22app%22%7D%7D">`,
+		})
+		app := base.Clone()
+
+		setPath(app, item.key, item.value)
+		assert.Equal(t, item.result, app.Config(), item.key)
+		assert.Equal(t, map[string]interface{}{
+			"APP": map[string]interface{}{"name": "app"},
+		}, base.Config(), item.key)
+	}
+}
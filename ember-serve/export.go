@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/256dpi/ember"
+	"github.com/256dpi/ember/fastboot"
+)
+
+func export(args []string) {
+	// parse flags
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	name := fs.String("name", "example", "The Ember.js application name.")
+	archiveDir := fs.String("archive-dir", "", "The directory inside the archive that contains the build.")
+	routesFile := fs.String("routes", "", "The file listing the routes to render, one per line.")
+	out := fs.String("out", "public", "The directory to write the exported files to.")
+	origin := fs.String("origin", "http://localhost:8000", "The origin of the application.")
+	timeout := fs.Duration("timeout", 5*time.Second, "The timeout for rendering pages.")
+	headed := fs.Bool("headed", false, "Whether to run in headed mode (visible Chrome window).")
+	shell := fs.String("shell", "_empty.html", "The file to write the unrendered index to for routes that are not exported.")
+	_ = fs.Parse(args)
+
+	// create app
+	files, err := loadFiles(fs.Arg(0), *archiveDir)
+	if err != nil {
+		panic(err)
+	}
+	app := ember.MustCreate(*name, files)
+
+	// read routes
+	routes := []string{"/"}
+	if *routesFile != "" {
+		data, err := os.ReadFile(*routesFile)
+		if err != nil {
+			panic(err)
+		}
+		routes = nil
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				routes = append(routes, line)
+			}
+		}
+	}
+
+	// run export
+	failures, err := fastboot.Export(fastboot.ExportOptions{
+		App:     app,
+		Origin:  *origin,
+		Routes:  routes,
+		Dir:     *out,
+		Timeout: *timeout,
+		Headed:  *headed,
+		Shell:   *shell,
+		OnRoute: func(route string, err error) {
+			if err != nil {
+				fmt.Printf("==> Failed: %s (%s)\n", route, err.Error())
+			} else {
+				fmt.Printf("==> Rendered: %s\n", route)
+			}
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// check failures
+	if len(failures) > 0 {
+		fmt.Printf("==> %d of %d routes failed\n", len(failures), len(routes))
+		os.Exit(1)
+	}
+}
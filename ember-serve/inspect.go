@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/256dpi/serve"
+
+	"github.com/256dpi/ember"
+)
+
+type inspection struct {
+	Name     string                 `json:"name"`
+	Config   map[string]interface{} `json:"config"`
+	Files    []inspectedFile        `json:"files"`
+	Manifest interface{}            `json:"manifest"`
+	Problems []ember.Problem        `json:"problems"`
+}
+
+type inspectedFile struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+	Type string `json:"type"`
+}
+
+func inspect(args []string) {
+	// parse flags
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	name := fs.String("name", "example", "The Ember.js application name.")
+	archiveDir := fs.String("archive-dir", "", "The directory inside the archive that contains the build.")
+	asJSON := fs.Bool("json", false, "Whether to print the inspection as JSON.")
+	_ = fs.Parse(args)
+
+	// load files
+	files, err := loadFiles(fs.Arg(0), *archiveDir)
+	if err != nil {
+		panic(err)
+	}
+
+	// inspect app
+	result, err := inspectApp(*name, files)
+	if err != nil {
+		panic(err)
+	}
+
+	// print inspection
+	err = printInspection(os.Stdout, result, *asJSON)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func inspectApp(name string, files map[string]string) (*inspection, error) {
+	// create app
+	app, err := ember.Create(name, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare inspection
+	result := &inspection{
+		Name:     app.Name(),
+		Config:   app.Config(),
+		Problems: app.Verify(),
+	}
+
+	// collect files
+	for file, content := range files {
+		result.Files = append(result.Files, inspectedFile{
+			Name: file,
+			Size: len(content),
+			Type: serve.MimeTypeByExtension(path.Ext(file), true),
+		})
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Name < result.Files[j].Name
+	})
+
+	// parse manifest
+	if packageJSON := app.File("package.json"); packageJSON != nil {
+		var pkg struct {
+			Fastboot interface{} `json:"fastboot"`
+		}
+		err = json.Unmarshal(packageJSON, &pkg)
+		if err != nil {
+			return nil, err
+		}
+		result.Manifest = pkg.Fastboot
+	}
+
+	return result, nil
+}
+
+func printInspection(w io.Writer, result *inspection, asJSON bool) error {
+	// print JSON
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	// print config
+	config, err := json.MarshalIndent(result.Config, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "==> Config (%s)\n%s\n\n", result.Name, config)
+
+	// print files
+	_, _ = fmt.Fprintln(w, "==> Files")
+	for _, file := range result.Files {
+		_, _ = fmt.Fprintf(w, "%10d  %-40s  %s\n", file.Size, file.Type, file.Name)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	// print manifest
+	_, _ = fmt.Fprintln(w, "==> FastBoot Manifest")
+	if result.Manifest != nil {
+		manifest, err := json.MarshalIndent(result.Manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "%s\n\n", manifest)
+	} else {
+		_, _ = fmt.Fprint(w, "none\n\n")
+	}
+
+	// print problems
+	_, _ = fmt.Fprintln(w, "==> Problems")
+	if len(result.Problems) == 0 {
+		_, _ = fmt.Fprintln(w, "none")
+	}
+	for _, problem := range result.Problems {
+		_, _ = fmt.Fprintln(w, problem.String())
+	}
+
+	return nil
+}
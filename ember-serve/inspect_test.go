@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var inspectFiles = map[string]string{
+	"index.html": `<html><head>
+<meta name="app/config/environment" content="%7B%22modulePrefix%22%3A%22app%22%7D"/>
+</head><body><script src="/assets/app.js"></script></body></html>`,
+	"package.json": `{"fastboot":{"manifest":{"appFiles":[]}}}`,
+	"robots.txt":   "",
+}
+
+func TestInspectApp(t *testing.T) {
+	result, err := inspectApp("app", inspectFiles)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = printInspection(&buf, result, true)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "app",
+		"config": {
+			"modulePrefix": "app"
+		},
+		"files": [
+			{"name": "index.html", "size": 163, "type": "text/html; charset=utf-8"},
+			{"name": "package.json", "size": 41, "type": "application/json"},
+			{"name": "robots.txt", "size": 0, "type": "text/plain; charset=utf-8"}
+		],
+		"manifest": {
+			"manifest": {
+				"appFiles": []
+			}
+		},
+		"problems": [
+			{"kind": "missing-asset", "file": "assets/app.js", "message": "referenced asset does not exist"}
+		]
+	}`, buf.String())
+
+	buf.Reset()
+	err = printInspection(&buf, result, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "==> Config (app)\n"+
+		"{\n  \"modulePrefix\": \"app\"\n}\n\n"+
+		"==> Files\n"+
+		"       163  text/html; charset=utf-8                  index.html\n"+
+		"        41  application/json                          package.json\n"+
+		"         0  text/plain; charset=utf-8                 robots.txt\n\n"+
+		"==> FastBoot Manifest\n"+
+		"{\n  \"manifest\": {\n    \"appFiles\": []\n  }\n}\n\n"+
+		"==> Problems\n"+
+		"missing-asset: referenced asset does not exist (assets/app.js)\n", buf.String())
+
+	_, err = inspectApp("app", map[string]string{
+		"index.html":   inspectFiles["index.html"],
+		"package.json": "{",
+	})
+	assert.Error(t, err)
+}
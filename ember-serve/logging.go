@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+func newLogger(format string) (*slog.Logger, error) {
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil)), nil
+	case "combined":
+		return slog.New(&combinedHandler{out: os.Stdout, mutex: &sync.Mutex{}}), nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
+// combinedHandler writes request records in the Apache combined log format
+// and other records as plain lines.
+type combinedHandler struct {
+	out   io.Writer
+	mutex *sync.Mutex
+}
+
+func (h *combinedHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *combinedHandler) Handle(_ context.Context, record slog.Record) error {
+	// collect attributes
+	attrs := map[string]slog.Value{}
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value
+		return true
+	})
+
+	// get string attribute or dash
+	get := func(key string) string {
+		value, ok := attrs[key]
+		if !ok || value.String() == "" {
+			return "-"
+		}
+		return value.String()
+	}
+
+	// get host
+	host := get("remote")
+	if i := strings.LastIndex(host, ":"); i > 0 {
+		host = host[:i]
+	}
+
+	// format line
+	line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %s %s %q %q\n",
+		host,
+		record.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		get("method"),
+		get("path"),
+		get("proto"),
+		get("status"),
+		get("bytes"),
+		get("referer"),
+		get("user_agent"),
+	)
+
+	// handle other records
+	if record.Message != "request" {
+		line = fmt.Sprintf("[%s] %s %s\n", record.Time.Format(time.RFC3339), record.Level, record.Message)
+	}
+
+	// write line
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := io.WriteString(h.out, line)
+
+	return err
+}
+
+func (h *combinedHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *combinedHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinedHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := &combinedHandler{out: &buf, mutex: &sync.Mutex{}}
+
+	now := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+
+	record := slog.NewRecord(now, slog.LevelInfo, "request", 0)
+	record.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/blog"),
+		slog.String("proto", "HTTP/1.1"),
+		slog.Int("status", 200),
+		slog.Int64("bytes", 1234),
+		slog.Duration("duration", time.Millisecond),
+		slog.String("kind", "cached"),
+		slog.String("remote", "192.0.2.1:1234"),
+		slog.String("referer", "https://example.org/"),
+		slog.String("user_agent", "Mozilla/5.0"),
+	)
+	err := handler.Handle(context.Background(), record)
+	assert.NoError(t, err)
+
+	record = slog.NewRecord(now, slog.LevelWarn, "request", 0)
+	record.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/"),
+		slog.String("proto", "HTTP/2.0"),
+		slog.Int("status", 200),
+		slog.Int64("bytes", 0),
+		slog.String("kind", "fallback"),
+		slog.String("remote", "[::1]:5678"),
+		slog.String("referer", ""),
+		slog.String("user_agent", ""),
+		slog.String("error", "pool closed"),
+	)
+	err = handler.Handle(context.Background(), record)
+	assert.NoError(t, err)
+
+	record = slog.NewRecord(now, slog.LevelWarn, "warm", 0)
+	record.AddAttrs(slog.String("url", "/"))
+	err = handler.Handle(context.Background(), record)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ""+
+		`192.0.2.1 - - [01/Mar/2024:12:30:45 +0000] "GET /blog HTTP/1.1" 200 1234 "https://example.org/" "Mozilla/5.0"`+"\n"+
+		`[::1] - - [01/Mar/2024:12:30:45 +0000] "GET / HTTP/2.0" 200 0 "-" "-"`+"\n"+
+		`[2024-03-01T12:30:45Z] WARN warm`+"\n", buf.String())
+}
+
+func TestNewLogger(t *testing.T) {
+	for _, format := range []string{"", "text", "json", "combined"} {
+		logger, err := newLogger(format)
+		assert.NoError(t, err, format)
+		assert.NotNil(t, logger, format)
+	}
+
+	_, err := newLogger("xml")
+	assert.EqualError(t, err, "invalid log format: xml")
+}
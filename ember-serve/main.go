@@ -1,80 +1,243 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/kr/pretty"
-
 	"github.com/256dpi/ember"
 	"github.com/256dpi/ember/fastboot"
 )
 
+var configFile = flag.String("config", "", "The JSON config file describing the server and apps.")
 var name = flag.String("name", "example", "The Ember.js application name.")
 var render = flag.Bool("fastboot", false, "Whether to render the application using FastBoot.")
 var timeout = flag.Duration("timeout", 5*time.Second, "The timeout for rendering pages.")
 var cache = flag.Duration("cache", 0, "The duration for which to cache rendered pages.")
+var cacheStale = flag.Duration("cache-stale", 0, "The duration for which expired pages are served while they are re-rendered.")
+var cacheSize = flag.Int64("cache-size", fastboot.DefaultCacheSize, "The maximum size in bytes of the in-memory render cache.")
+var cacheDir = flag.String("cache-dir", "", "The directory in which to persist rendered pages instead of memory.")
+var warmSitemap = flag.String("warm-sitemap", "", "The sitemap file in the build whose URLs are rendered into the cache on startup.")
+var purgePath = flag.String("purge-path", "", "The path of the endpoint that purges the render cache.")
+var purgeToken = flag.String("purge-token", "", "The bearer token required by the purge endpoint.")
 var isolated = flag.Bool("isolated", false, "Whether to boot the application per request.")
+var poolSize = flag.Int("pool", 1, "The number of FastBoot instances to render concurrently.")
 var origin = flag.String("origin", "http://localhost:8000", "The origin of the application.")
 var addr = flag.String("addr", ":8000", "The address to listen on.")
 var headed = flag.Bool("headed", false, "Whether to run in headed mode (visible Chrome window).")
-var log = flag.Bool("log", false, "Whether to log requests and results.")
+var log = flag.Bool("log", false, "Whether to log requests.")
+var logFormat = flag.String("log-format", "text", "The request log format (text, json or combined).")
+var metrics = flag.String("metrics", "", "The path on which to serve Prometheus metrics.")
+var archiveDir = flag.String("archive-dir", "", "The directory inside the archive that contains the build.")
+var prefix = flag.String("prefix", "", "The path prefix under which the application is served.")
+var tlsCert = flag.String("tls-cert", "", "The TLS certificate file to serve HTTPS.")
+var tlsKey = flag.String("tls-key", "", "The TLS key file to serve HTTPS.")
+var tlsSelfSigned = flag.Bool("tls-self-signed", false, "Whether to serve HTTPS using a generated self-signed certificate.")
+var shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "The time to wait for in-flight requests on shutdown.")
+var watch = flag.Bool("watch", false, "Whether to watch the sources and reload on changes.")
+var proxies listFlag
+var settings listFlag
+var headFiles listFlag
+var inlineScripts listFlag
+var warmURLs listFlag
+
+func init() {
+	flag.Var(&proxies, "proxy", "A reverse proxy rule in the form /prefix=http://host:port (repeatable).")
+	flag.Var(&settings, "set", "A config override in the form key.path=value (repeatable).")
+	flag.Var(&headFiles, "head-file", "A file whose contents are appended to the head tag (repeatable).")
+	flag.Var(&inlineScripts, "inline-script", "A JS file that is added as an inline script (repeatable).")
+	flag.Var(&warmURLs, "warm", "A URL that is rendered into the cache on startup (repeatable).")
+}
 
 func main() {
+	// handle subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			inspect(os.Args[2:])
+			return
+		case "export":
+			export(os.Args[2:])
+			return
+		}
+	}
+
 	// parse flags
 	flag.Parse()
 
-	// get path
-	path, err := filepath.Abs(flag.Arg(0))
+	// get config
+	var config *serverConfig
+	var err error
+	if *configFile != "" {
+		config, err = loadConfig(*configFile)
+	} else {
+		config, err = flagsConfig()
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	// prepare server
+	server := &http.Server{
+		Addr: config.Addr,
+	}
+
+	// build handler
+	var renderers func() []*fastboot.Handler
+	if config.Watch {
+		// create watcher
+		watcher, err := newWatcher(config)
+		if err != nil {
+			fail(err)
+		}
+
+		// run watcher
+		go watcher.Run(time.Duration(config.WatchInterval))
+		server.RegisterOnShutdown(watcher.Close)
+
+		// set handler
+		server.Handler = watcher
+		renderers = watcher.Renderers
+	} else {
+		// build handler
+		handler, list, err := config.build()
+		if err != nil {
+			for _, renderer := range list {
+				renderer.Close()
+			}
+			fail(err)
+		}
+
+		// set handler
+		server.Handler = handler
+		renderers = func() []*fastboot.Handler {
+			return list
+		}
+	}
+
+	// generate certificate
+	if config.TLSSelfSigned {
+		cert, err := selfSignedCertificate()
+		if err != nil {
+			fail(err)
+		}
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	// run server
+	err = run(server, config.secure(), config.TLSCert, config.TLSKey, time.Duration(config.ShutdownTimeout), renderers)
 	if err != nil {
-		panic(err)
+		fail(err)
 	}
+}
 
-	// get directory
-	dir := filepath.Base(path)
-	path = filepath.Dir(path)
+func fail(err error) {
+	_, _ = fmt.Println("==> Error: " + err.Error())
+	os.Exit(1)
+}
 
-	// prepare files
-	files := ember.MustFiles(os.DirFS(path), dir)
+func flagsConfig() (*serverConfig, error) {
+	// prepare app
+	app := appConfig{
+		Source:        flag.Arg(0),
+		ArchiveDir:    *archiveDir,
+		Name:          *name,
+		Prefix:        *prefix,
+		Set:           map[string]interface{}{},
+		HeadFiles:     headFiles,
+		InlineScripts: inlineScripts,
+	}
 
-	// create app
-	app := ember.MustCreate(*name, files)
+	// parse settings
+	for _, setting := range settings {
+		key, value, err := parseSetting(setting)
+		if err != nil {
+			return nil, err
+		}
+		app.Set[key] = value
+	}
 
-	// create handler
-	var handler http.Handler = app
+	// check purge token
+	if *purgePath != "" && *purgeToken == "" {
+		return nil, fmt.Errorf("missing -purge-token for -purge-path")
+	}
 
 	// handle fastboot
 	if *render {
-		handler, err = fastboot.Handle(fastboot.Options{
-			App:      app,
-			Origin:   *origin,
-			Timeout:  *timeout,
-			Cache:    *cache,
-			Isolated: *isolated,
-			Headed:   *headed,
-			OnRequest: func(request *fastboot.Request) {
-				if *log {
-					_, _ = pretty.Println("==> Request", request)
-				}
-			},
-			OnResult: func(result *fastboot.Result) {
-				if *log {
-					_, _ = pretty.Println("==> Result", result)
-				}
-			},
-			OnError: func(err error) {
-				_, _ = fmt.Println("==> Error: " + err.Error())
-			},
-		})
+		app.FastBoot = &fastbootConfig{
+			Timeout:     duration(*timeout),
+			Cache:       duration(*cache),
+			CacheStale:  duration(*cacheStale),
+			CacheSize:   *cacheSize,
+			CacheDir:    *cacheDir,
+			WarmURLs:    warmURLs,
+			WarmSitemap: *warmSitemap,
+			PurgePath:   *purgePath,
+			PurgeToken:  *purgeToken,
+			Isolated:    *isolated,
+			PoolSize:    *poolSize,
+			Origin:      *origin,
+			Headed:      *headed,
+		}
+	}
+
+	// prepare proxies
+	proxyMap := map[string]string{}
+	for _, rule := range proxies {
+		_, err := parseProxy(rule)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
+		prefix, target, _ := strings.Cut(rule, "=")
+		proxyMap[prefix] = target
 	}
 
-	// run server
-	panic(http.ListenAndServe(*addr, handler))
+	// prepare config
+	config := &serverConfig{
+		Addr:            *addr,
+		TLSCert:         *tlsCert,
+		TLSKey:          *tlsKey,
+		TLSSelfSigned:   *tlsSelfSigned,
+		Metrics:         *metrics,
+		Log:             *log,
+		LogFormat:       *logFormat,
+		ShutdownTimeout: duration(*shutdownTimeout),
+		Watch:           *watch,
+		Proxies:         proxyMap,
+		Apps:            []appConfig{app},
+	}
+
+	// apply defaults
+	config.defaults()
+
+	return config, nil
+}
+
+func loadFiles(path, archiveDir string) (map[string]string, error) {
+	// get path
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle archives
+	if format := ember.ArchiveFormat(path); format != "" {
+		// open archive
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		return ember.FilesFromArchive(file, format, archiveDir)
+	}
+
+	return ember.Files(os.DirFS(filepath.Dir(path)), filepath.Base(path))
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type proxyRule struct {
+	prefix string
+	proxy  *httputil.ReverseProxy
+}
+
+func parseProxy(rule string) (proxyRule, error) {
+	// split rule
+	prefix, target, ok := strings.Cut(rule, "=")
+	if !ok || !strings.HasPrefix(prefix, "/") {
+		return proxyRule{}, fmt.Errorf("invalid proxy rule: %s", rule)
+	}
+
+	// parse target
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return proxyRule{}, err
+	} else if targetURL.Scheme == "" || targetURL.Host == "" {
+		return proxyRule{}, fmt.Errorf("invalid proxy target: %s", target)
+	}
+
+	// prepare proxy, websocket upgrades are handled by the reverse proxy
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			// rewrite URL and host
+			r.SetURL(targetURL)
+
+			// set forwarded headers
+			r.SetXForwarded()
+		},
+	}
+
+	return proxyRule{
+		prefix: strings.TrimRight(prefix, "/"),
+		proxy:  proxy,
+	}, nil
+}
+
+func proxyHandler(rules []string, next http.Handler) (http.Handler, error) {
+	// skip if empty
+	if len(rules) == 0 {
+		return next, nil
+	}
+
+	// parse rules
+	var list []proxyRule
+	for _, rule := range rules {
+		parsed, err := parseProxy(rule)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, parsed)
+	}
+
+	// sort by most specific prefix
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i].prefix) > len(list[j].prefix)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// proxy matching requests
+		for _, rule := range list {
+			if r.URL.Path == rule.prefix || strings.HasPrefix(r.URL.Path, rule.prefix+"/") {
+				rule.proxy.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		// otherwise, fall through
+		next.ServeHTTP(w, r)
+	}), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyHandler(t *testing.T) {
+	upstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name + " " + r.URL.Path))
+		}))
+	}
+
+	api := upstream("api")
+	defer api.Close()
+	auth := upstream("auth")
+	defer auth.Close()
+
+	handler, err := proxyHandler([]string{
+		"/api=" + api.URL,
+		"/api/auth/=" + auth.URL,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("app " + r.URL.Path))
+	}))
+	assert.NoError(t, err)
+
+	for path, result := range map[string]string{
+		"/":                "app /",
+		"/apis":            "app /apis",
+		"/api":             "api /api",
+		"/api/users":       "api /api/users",
+		"/api/auth":        "auth /api/auth",
+		"/api/auth/login":  "auth /api/auth/login",
+		"/api/authorize":   "api /api/authorize",
+		"/other/api/users": "app /other/api/users",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, result, rec.Body.String(), path)
+	}
+}
+
+func TestParseProxy(t *testing.T) {
+	for _, item := range []struct {
+		rule   string
+		prefix string
+		err    string
+	}{
+		{rule: "/api=http://localhost:3000", prefix: "/api"},
+		{rule: "/api/=http://localhost:3000/v1", prefix: "/api"},
+		{rule: "api=http://localhost:3000", err: "invalid proxy rule: api=http://localhost:3000"},
+		{rule: "/api", err: "invalid proxy rule: /api"},
+		{rule: "/api=localhost:3000", err: "invalid proxy target: localhost:3000"},
+		{rule: "/api=/foo", err: "invalid proxy target: /foo"},
+	} {
+		rule, err := parseProxy(item.rule)
+		if item.err != "" {
+			assert.EqualError(t, err, item.err, item.rule)
+			continue
+		}
+		assert.NoError(t, err, item.rule)
+		assert.Equal(t, item.prefix, rule.prefix, item.rule)
+	}
+
+	handler := http.NotFoundHandler()
+	result, err := proxyHandler(nil, handler)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+
+	_, err = proxyHandler([]string{"invalid"}, handler)
+	assert.Error(t, err)
+}
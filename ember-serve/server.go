@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/256dpi/ember/fastboot"
+)
+
+func run(server *http.Server, secure bool, certFile, keyFile string, timeout time.Duration, renderers func() []*fastboot.Handler) error {
+	// handle signals
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// run server (HTTP/2 is enabled automatically for TLS)
+	errs := make(chan error, 1)
+	go func() {
+		if secure {
+			errs <- server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			errs <- server.ListenAndServe()
+		}
+	}()
+
+	// await error or signal
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// log shutdown
+	_, _ = fmt.Println("==> Shutting down...")
+
+	// prepare deadline
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// drain in-flight requests
+	err := server.Shutdown(shutdownCtx)
+
+	// shutdown renderers
+	for _, renderer := range renderers() {
+		err = errors.Join(err, renderer.Shutdown(shutdownCtx))
+	}
+
+	// report timeout
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("shutdown did not complete within %s: %w", timeout, err)
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := selfSignedCertificate()
+	assert.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	assert.NoError(t, leaf.VerifyHostname("localhost"))
+	assert.NoError(t, leaf.VerifyHostname("127.0.0.1"))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs: roots,
+			},
+			ForceAttemptHTTP2: true,
+		},
+	}
+
+	res, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "HTTP/2.0", res.Proto)
+	assert.NotNil(t, res.TLS)
+	assert.Equal(t, "h2", res.TLS.NegotiatedProtocol)
+}
+
+func TestSecureOrigin(t *testing.T) {
+	for origin, result := range map[string]string{
+		"http://localhost:8000":  "https://localhost:8000",
+		"https://localhost:8000": "https://localhost:8000",
+		"http://example.org":     "https://example.org",
+		"example.org":            "example.org",
+	} {
+		assert.Equal(t, result, secureOrigin(origin), origin)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/256dpi/ember/fastboot"
+)
+
+const liveReloadPath = "/_ember/livereload"
+
+const liveReloadScript = `(function () {
+  var source = new EventSource('` + liveReloadPath + `');
+  source.onmessage = function (event) {
+    if (event.data === 'css') {
+      document.querySelectorAll('link[rel="stylesheet"]').forEach(function (link) {
+        var url = new URL(link.href);
+        url.searchParams.set('livereload', Date.now());
+        link.href = url.toString();
+      });
+    } else {
+      window.location.reload();
+    }
+  };
+})();`
+
+type fileState struct {
+	modified time.Time
+	size     int64
+	hash     [sha1.Size]byte
+}
+
+type watcher struct {
+	config    *serverConfig
+	handler   http.Handler
+	renderers []*fastboot.Handler
+	state     map[string]fileState
+	clients   map[chan string]struct{}
+	closing   chan struct{}
+	once      sync.Once
+	mutex     sync.RWMutex
+}
+
+func newWatcher(config *serverConfig) (*watcher, error) {
+	// prepare watcher
+	w := &watcher{
+		config:  config,
+		clients: map[chan string]struct{}{},
+		closing: make(chan struct{}),
+	}
+
+	// get initial state
+	w.state, _ = w.scan(nil)
+
+	// build handler
+	err := w.rebuild()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// handle live reload
+	if r.URL.Path == liveReloadPath {
+		w.stream(rw, r)
+		return
+	}
+
+	// get handler
+	w.mutex.RLock()
+	handler := w.handler
+	w.mutex.RUnlock()
+
+	// serve request
+	handler.ServeHTTP(rw, r)
+}
+
+func (w *watcher) Renderers() []*fastboot.Handler {
+	// acquire mutex
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.renderers
+}
+
+func (w *watcher) Run(interval time.Duration) {
+	// prepare ticker
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// await tick
+		select {
+		case <-ticker.C:
+		case <-w.closing:
+			return
+		}
+
+		// scan sources
+		state, changed := w.scan(w.state)
+		w.state = state
+		if len(changed) == 0 {
+			continue
+		}
+
+		// rebuild handler
+		err := w.rebuild()
+		if err != nil {
+			_, _ = fmt.Println("==> Error: " + err.Error())
+			continue
+		}
+
+		// notify clients
+		_, _ = fmt.Printf("==> Reloaded (%d changed files)\n", len(changed))
+		w.broadcast(changeEvent(changed))
+	}
+}
+
+func (w *watcher) Close() {
+	w.once.Do(func() {
+		close(w.closing)
+	})
+}
+
+func (w *watcher) rebuild() error {
+	// build handler
+	handler, renderers, err := w.config.build()
+	if err != nil {
+		for _, renderer := range renderers {
+			renderer.Close()
+		}
+		return err
+	}
+
+	// swap handler
+	w.mutex.Lock()
+	oldRenderers := w.renderers
+	w.handler = handler
+	w.renderers = renderers
+	w.mutex.Unlock()
+
+	// drain old renderers
+	for _, renderer := range oldRenderers {
+		go func(renderer *fastboot.Handler) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(w.config.ShutdownTimeout))
+			defer cancel()
+			_ = renderer.Shutdown(ctx)
+		}(renderer)
+	}
+
+	return nil
+}
+
+func (w *watcher) scan(previous map[string]fileState) (map[string]fileState, []string) {
+	// prepare state
+	state := map[string]fileState{}
+	var changed []string
+
+	// walk sources
+	for _, app := range w.config.Apps {
+		_ = filepath.WalkDir(app.Source, func(path string, d fs.DirEntry, err error) error {
+			// skip errors and directories
+			if err != nil || d.IsDir() {
+				return nil
+			}
+
+			// get info
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+
+			// reuse hash if unchanged
+			prev, ok := previous[path]
+			if ok && prev.modified.Equal(info.ModTime()) && prev.size == info.Size() {
+				state[path] = prev
+				return nil
+			}
+
+			// hash file
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			current := fileState{
+				modified: info.ModTime(),
+				size:     info.Size(),
+				hash:     sha1.Sum(data),
+			}
+			state[path] = current
+
+			// check change
+			if previous != nil && (!ok || prev.hash != current.hash) {
+				changed = append(changed, path)
+			}
+
+			return nil
+		})
+	}
+
+	// check removed files
+	for path := range previous {
+		if _, ok := state[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	return state, changed
+}
+
+func changeEvent(changed []string) string {
+	// reload stylesheets only if all changes are stylesheets
+	for _, name := range changed {
+		if !strings.HasSuffix(name, ".css") {
+			return "reload"
+		}
+	}
+
+	return "css"
+}
+
+func (w *watcher) stream(rw http.ResponseWriter, r *http.Request) {
+	// get flusher
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "", http.StatusInternalServerError)
+		return
+	}
+
+	// register client
+	ch := make(chan string, 1)
+	w.mutex.Lock()
+	w.clients[ch] = struct{}{}
+	w.mutex.Unlock()
+
+	// ensure client is removed
+	defer func() {
+		w.mutex.Lock()
+		delete(w.clients, ch)
+		w.mutex.Unlock()
+	}()
+
+	// write headers
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// stream events
+	for {
+		select {
+		case event := <-ch:
+			_, _ = fmt.Fprintf(rw, "data: %s\n\n", event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-w.closing:
+			return
+		}
+	}
+}
+
+func (w *watcher) broadcast(event string) {
+	// acquire mutex
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	// notify clients
+	for ch := range w.clients {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func watchSource(t *testing.T, environment string) string {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html><head>
+		<meta name="example/config/environment" content="%7B%22environment%22%3A%22`+environment+`%22%7D"/>
+		</head><body></body></html>`), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "app.css"), []byte("body {}"), 0644)
+	assert.NoError(t, err)
+	return dir
+}
+
+func TestWatcherScan(t *testing.T) {
+	dir := watchSource(t, "development")
+	w := &watcher{config: &serverConfig{Apps: []appConfig{{Source: dir}}}}
+
+	state, changed := w.scan(nil)
+	assert.Len(t, state, 2)
+	assert.Empty(t, changed)
+
+	state, changed = w.scan(state)
+	assert.Empty(t, changed)
+
+	file := filepath.Join(dir, "app.css")
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(file, later, later))
+	state, changed = w.scan(state)
+	assert.Empty(t, changed)
+
+	assert.NoError(t, os.WriteFile(file, []byte("body { color: red }"), 0644))
+	state, changed = w.scan(state)
+	assert.Equal(t, []string{file}, changed)
+
+	added := filepath.Join(dir, "app.js")
+	assert.NoError(t, os.WriteFile(added, []byte("1"), 0644))
+	state, changed = w.scan(state)
+	assert.Equal(t, []string{added}, changed)
+
+	assert.NoError(t, os.Remove(added))
+	state, changed = w.scan(state)
+	assert.Equal(t, []string{added}, changed)
+	assert.Len(t, state, 2)
+}
+
+func TestChangeEvent(t *testing.T) {
+	assert.Equal(t, "css", changeEvent([]string{"assets/app.css"}))
+	assert.Equal(t, "css", changeEvent([]string{"assets/app.css", "assets/vendor.css"}))
+	assert.Equal(t, "reload", changeEvent([]string{"assets/app.css", "assets/app.js"}))
+	assert.Equal(t, "reload", changeEvent([]string{"index.html"}))
+}
+
+func TestWatcherStream(t *testing.T) {
+	w, err := newWatcher(&serverConfig{
+		Watch: true,
+		Apps:  []appConfig{{Source: watchSource(t, "development")}},
+	})
+	assert.NoError(t, err)
+	defer w.Close()
+
+	server := httptest.NewServer(w)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + liveReloadPath)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool {
+		w.mutex.RLock()
+		defer w.mutex.RUnlock()
+		return len(w.clients) == 1
+	}, time.Second, time.Millisecond)
+
+	reader := bufio.NewReader(res.Body)
+
+	w.broadcast("css")
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "data: css\n", line)
+
+	_, _ = reader.ReadString('\n')
+
+	w.broadcast("reload")
+	line, err = reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "data: reload\n", line)
+}
+
+func TestWatcherScript(t *testing.T) {
+	for environment, injected := range map[string]bool{
+		"development": true,
+		"production":  false,
+	} {
+		w, err := newWatcher(&serverConfig{
+			Watch: true,
+			Apps:  []appConfig{{Source: watchSource(t, environment)}},
+		})
+		assert.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code, environment)
+		assert.Equal(t, injected, strings.Contains(rec.Body.String(), liveReloadPath), environment)
+
+		w.Close()
+	}
+}
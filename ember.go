@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,9 +29,25 @@ type App struct {
 	files    map[string][]byte
 	index    [3][]byte
 	config   map[string]interface{}
+	previous []previous
+	metrics  Metrics
+	logger   *slog.Logger
 	modified time.Time
 }
 
+type previous struct {
+	files    map[string][]byte
+	modified time.Time
+	until    time.Time
+}
+
+// previousDirs are the directories from which files of previous builds are
+// served.
+var previousDirs = []string{"assets/", "engines-dist/"}
+
+// fingerprintPattern matches file names fingerprinted by ember-cli.
+var fingerprintPattern = regexp.MustCompile(`[-.][0-9a-f]{16,}(\.[a-z0-9]+)+$`)
+
 // MustCreate will call Create and panic on errors.
 func MustCreate(name string, files map[string]string) *App {
 	// create app
@@ -260,28 +279,104 @@ func (a *App) AddFile(name string, contents string) {
 	a.modified = time.Now()
 }
 
+// WithPrevious will add the provided app and its unexpired previous builds as
+// previous builds of the app. Assets and fingerprinted files that are missing
+// in the current build are looked up in previous builds until the grace period
+// expires. A zero grace period keeps the previous build available
+// indefinitely. The index is always served from the current build.
+func (a *App) WithPrevious(old *App, grace time.Duration) {
+	// copy previous if missing
+	a.copyPrevious()
+
+	// determine deadline
+	var until time.Time
+	if grace > 0 {
+		until = time.Now().Add(grace)
+	}
+
+	// add previous (only the files are kept to not retain the old app)
+	a.previous = append(a.previous, previous{
+		files:    old.getFiles(),
+		modified: old.modified,
+		until:    until,
+	})
+	a.previous = append(a.previous, old.getPrevious()...)
+
+	// remove expired builds
+	now := time.Now()
+	list := make([]previous, 0, len(a.previous))
+	for _, prev := range a.previous {
+		if prev.until.IsZero() || now.Before(prev.until) {
+			list = append(list, prev)
+		}
+	}
+	a.previous = list
+}
+
+// WithMetrics will set the metrics that are used to record served requests.
+func (a *App) WithMetrics(metrics Metrics) {
+	a.metrics = metrics
+}
+
+// WithLogger will set the logger that is used to log served requests.
+func (a *App) WithLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
 // IsPage will return whether the provided path matches a page.
 func (a *App) IsPage(path string) bool {
 	path = strings.Trim(path, "/")
-	return path == indexHTMLFile || a.getFiles()[path] == nil
+	_, _, ok := a.lookup(path)
+	return path == indexHTMLFile || !ok
 }
 
 // IsAsset will return whether the provided path matches an asset.
 func (a *App) IsAsset(path string) bool {
 	path = strings.Trim(path, "/")
-	return path != indexHTMLFile && a.getFiles()[path] != nil
+	_, _, ok := a.lookup(path)
+	return path != indexHTMLFile && ok
+}
+
+// Files returns the sorted names of all files of the current build.
+func (a *App) Files() []string {
+	// collect names
+	names := make([]string, 0, len(a.getFiles()))
+	for name := range a.getFiles() {
+		names = append(names, name)
+	}
+
+	// sort names
+	sort.Strings(names)
+
+	return names
 }
 
 // File returns the contents of the specified file.
 func (a *App) File(path string) []byte {
-	return a.getFiles()[path]
+	content, _, _ := a.lookup(path)
+	return content
 }
 
 // ServeHTTP implements the http.Handler interface.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// get start
+	start := time.Now()
+
+	// wrap writer if logging
+	logger := a.getLogger()
+	var aw *AccessWriter
+	if logger != nil {
+		aw = &AccessWriter{ResponseWriter: w}
+		w = aw
+	}
+
 	// check method
 	if r.Method != "GET" {
 		http.Error(w, "", http.StatusMethodNotAllowed)
+		a.record("ember_app_requests", "invalid", start)
+		if logger != nil {
+			LogAccess(logger, r, aw, "invalid", start, nil)
+		}
 		return
 	}
 
@@ -289,10 +384,12 @@ func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	pth := strings.Trim(r.URL.Path, "/")
 
 	// get content
-	content, ok := a.getFiles()[pth]
-	if !ok {
+	outcome := "asset"
+	content, modified, ok := a.lookup(pth)
+	if !ok || pth == indexHTMLFile {
+		outcome = "index"
 		pth = indexHTMLFile
-		content, _ = a.getFiles()[pth]
+		content, modified, _ = a.lookup(pth)
 	}
 
 	// set content type
@@ -300,7 +397,15 @@ func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", mimeType)
 
 	// serve file
-	http.ServeContent(w, r, pth, a.modified, bytes.NewReader(content))
+	http.ServeContent(w, r, pth, modified, bytes.NewReader(content))
+
+	// record request
+	a.record("ember_app_requests", outcome, start)
+
+	// log request
+	if logger != nil {
+		LogAccess(logger, r, aw, outcome, start, nil)
+	}
 }
 
 // Handler will construct and return a dynamic handler that invokes the provided
@@ -315,8 +420,10 @@ func (a *App) Handler(configure func(*App, *http.Request)) http.Handler {
 		}
 
 		// configure clone
+		start := time.Now()
 		clone := a.Clone()
 		configure(clone, r)
+		a.record("ember_app_configure", "success", start)
 
 		// serve
 		clone.ServeHTTP(w, r)
@@ -332,6 +439,74 @@ func (a *App) Clone() *App {
 	}
 }
 
+func (a *App) lookup(path string) ([]byte, time.Time, bool) {
+	// check current files
+	content, ok := a.getFiles()[path]
+	if ok {
+		return content, a.modified, true
+	}
+
+	// only serve assets and fingerprinted files from previous builds
+	if !isPreviousFile(path) {
+		return nil, time.Time{}, false
+	}
+
+	// check previous builds
+	now := time.Now()
+	for _, prev := range a.getPrevious() {
+		if !prev.until.IsZero() && now.After(prev.until) {
+			continue
+		}
+		content, ok = prev.files[path]
+		if ok {
+			return content, prev.modified, true
+		}
+	}
+
+	return nil, time.Time{}, false
+}
+
+func isPreviousFile(path string) bool {
+	// check directories
+	for _, dir := range previousDirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+
+	return fingerprintPattern.MatchString(path)
+}
+
+func (a *App) record(name, outcome string, start time.Time) {
+	// get metrics
+	metrics := a.getMetrics()
+	if metrics == nil {
+		return
+	}
+
+	// record metrics
+	metrics.Count(name+"_total", outcome)
+	metrics.Observe(name+"_seconds", outcome, time.Since(start))
+}
+
+func (a *App) getMetrics() Metrics {
+	// check metrics
+	if a.metrics != nil || a.parent == nil {
+		return a.metrics
+	}
+
+	return a.parent.getMetrics()
+}
+
+func (a *App) getLogger() *slog.Logger {
+	// check logger
+	if a.logger != nil || a.parent == nil {
+		return a.logger
+	}
+
+	return a.parent.getLogger()
+}
+
 func (a *App) getFiles() map[string][]byte {
 	// check files
 	if a.files != nil {
@@ -369,3 +544,18 @@ func (a *App) copyConfig() {
 		}
 	}
 }
+
+func (a *App) getPrevious() []previous {
+	// check previous
+	if a.previous != nil || a.parent == nil {
+		return a.previous
+	}
+
+	return a.parent.getPrevious()
+}
+
+func (a *App) copyPrevious() {
+	if a.previous == nil && a.parent != nil {
+		a.previous = append([]previous{}, a.parent.getPrevious()...)
+	}
+}
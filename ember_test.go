@@ -1,7 +1,9 @@
 package ember
 
 import (
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,6 +32,7 @@ func TestApp(t *testing.T) {
 
 	app.AddFile("foo.html", "Hello World!")
 	assert.Equal(t, "Hello World!", string(app.File("foo.html")))
+	assert.Equal(t, []string{"app.css", "foo.html", "index.html", "script.js"}, app.Files())
 
 	app.AddInlineStyle("body { background: red; }")
 	app.AddInlineScript(`alert("Hello World!);"`)
@@ -112,3 +115,60 @@ func BenchmarkAppCloneSet(b *testing.B) {
 		app.Clone().Set("foo", "bar")
 	}
 }
+
+func TestAppPrevious(t *testing.T) {
+	older := MustCreate("app", map[string]string{
+		"index.html":                    indexHTML,
+		"assets/older.js":               "older",
+		"chunk.0123456789abcdef0123.js": "older",
+	})
+
+	old := MustCreate("app", map[string]string{
+		"index.html":    indexHTML,
+		"assets/old.js": "old",
+		"script.js":     "old",
+		"robots.txt":    "old",
+		"package.json":  "{}",
+		"app-0123456789abcdef0123456789abcdef.js": "old",
+	})
+	old.WithPrevious(older, 0)
+
+	app := MustCreate("app", files)
+	app.WithPrevious(old, 0)
+
+	assert.True(t, app.IsAsset("/assets/old.js"))
+	assert.False(t, app.IsPage("/assets/old.js"))
+	assert.Equal(t, "old", string(app.File("assets/old.js")))
+	assert.Equal(t, "old", string(app.File("app-0123456789abcdef0123456789abcdef.js")))
+	assert.Equal(t, "older", string(app.File("assets/older.js")))
+	assert.Equal(t, "older", string(app.File("chunk.0123456789abcdef0123.js")))
+	assert.Equal(t, scriptJS, string(app.File("script.js")))
+	assert.Nil(t, app.File("robots.txt"))
+	assert.Nil(t, app.File("package.json"))
+	assert.True(t, app.IsPage("/robots.txt"))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/assets/old.js", nil))
+	assert.Equal(t, "old", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/assets/missing.js", nil))
+	assert.Equal(t, indexHTML, rec.Body.String())
+
+	clone := app.Clone()
+	assert.Equal(t, "old", string(clone.File("assets/old.js")))
+
+	app = MustCreate("app", files)
+	app.WithPrevious(old, time.Millisecond)
+	assert.True(t, app.IsAsset("/assets/old.js"))
+	assert.Len(t, app.previous, 2)
+
+	time.Sleep(2 * time.Millisecond)
+	assert.False(t, app.IsAsset("/assets/old.js"))
+	assert.Nil(t, app.File("assets/old.js"))
+
+	app.WithPrevious(older, 0)
+	assert.Len(t, app.previous, 2)
+	assert.Nil(t, app.File("assets/old.js"))
+	assert.Equal(t, "older", string(app.File("assets/older.js")))
+}
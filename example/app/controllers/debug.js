@@ -4,8 +4,6 @@ import { service } from '@ember/service';
 export default class extends Controller {
   @service fastboot;
 
-  // TODO: Add shoebox support?
-
   get debug() {
     return JSON.stringify(
       {
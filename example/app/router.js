@@ -10,4 +10,6 @@ Router.map(function () {
   this.route('delay');
   this.route('fetch');
   this.route('debug');
+  this.route('status');
+  this.route('moved');
 });
@@ -1,8 +1,26 @@
 import Route from '@ember/routing/route';
+import { service } from '@ember/service';
 
 export default class extends Route {
+  @service fastboot;
+
   async model() {
+    // reuse data from shoebox
+    const shoebox = this.fastboot.shoebox;
+    const data = shoebox.retrieve('user');
+    if (data) {
+      return data;
+    }
+
+    // fetch data
     const res = await fetch(`https://api.github.com/users/256dpi`);
-    return await res.json();
+    const user = await res.json();
+
+    // store data in shoebox
+    if (this.fastboot.isFastBoot) {
+      shoebox.put('user', user);
+    }
+
+    return user;
   }
 }
@@ -0,0 +1,10 @@
+import Route from '@ember/routing/route';
+import { service } from '@ember/service';
+
+export default class extends Route {
+  @service router;
+
+  beforeModel() {
+    this.router.transitionTo('status');
+  }
+}
@@ -0,0 +1,22 @@
+import Route from '@ember/routing/route';
+import { service } from '@ember/service';
+
+export default class extends Route {
+  @service fastboot;
+
+  queryParams = {
+    code: {},
+    cookie: {},
+  };
+
+  model(params) {
+    if (this.fastboot.isFastBoot) {
+      this.fastboot.response.statusCode = parseInt(params.code) || 200;
+      this.fastboot.response.headers.set('cache-control', 'max-age=60');
+      if (params.cookie) {
+        this.fastboot.response.headers.append('set-cookie', params.cookie);
+      }
+    }
+    return params;
+  }
+}
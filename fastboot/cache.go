@@ -0,0 +1,348 @@
+package fastboot
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the default byte limit of the memory cache.
+const DefaultCacheSize = 64 << 20
+
+// Cache is a store for rendered pages. Implementations must be safe for
+// concurrent use and may be shared between multiple handlers and processes.
+type Cache interface {
+	// Get returns the value stored for the key if it has not yet expired.
+	Get(key string) ([]byte, bool)
+
+	// Set stores the value for the key for the specified duration.
+	Set(key string, value []byte, ttl time.Duration)
+
+	// Delete removes the value stored for the key.
+	Delete(key string)
+}
+
+// CacheScanner is an optional interface implemented by caches that can list
+// their keys. Purges delete matching entries directly from such caches, while
+// other caches store purge markers that are checked on every lookup.
+type CacheScanner interface {
+	// Keys returns the keys of all entries that have not yet expired.
+	Keys() []string
+}
+
+// CacheCleaner is an optional interface implemented by caches that need to
+// remove expired entries explicitly. Handlers call Clean periodically.
+type CacheCleaner interface {
+	// Clean removes all expired entries.
+	Clean() error
+}
+
+// MemoryCache is an in-memory cache that evicts the least recently used
+// entries once the total size of keys and values exceeds the limit.
+type MemoryCache struct {
+	limit   int64
+	size    int64
+	list    *list.List
+	entries map[string]*list.Element
+	mutex   sync.Mutex
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache will create and return a new memory cache with the provided
+// byte limit.
+func NewMemoryCache(limit int64) *MemoryCache {
+	return &MemoryCache{
+		limit:   limit,
+		list:    list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get implements the Cache interface.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// get element
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	// check expiry
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+
+	// mark as recently used
+	c.list.MoveToFront(element)
+
+	return entry.value, true
+}
+
+// Set implements the Cache interface.
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// remove existing entry
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	// skip entries that never fit
+	size := int64(len(key) + len(value))
+	if size > c.limit {
+		return
+	}
+
+	// add entry
+	c.entries[key] = c.list.PushFront(&memoryEntry{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(ttl),
+	})
+	c.size += size
+
+	// evict least recently used entries
+	for c.size > c.limit {
+		c.remove(c.list.Back())
+	}
+}
+
+// Delete implements the Cache interface.
+func (c *MemoryCache) Delete(key string) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// remove entry
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Keys implements the CacheScanner interface.
+func (c *MemoryCache) Keys() []string {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// collect keys
+	now := time.Now()
+	keys := make([]string, 0, len(c.entries))
+	for key, element := range c.entries {
+		if !now.After(element.Value.(*memoryEntry).expires) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Size returns the current size of all keys and values.
+func (c *MemoryCache) Size() int64 {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	entry := c.list.Remove(element).(*memoryEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.key) + len(entry.value))
+}
+
+// FileCache is a cache that stores entries as files in a directory to
+// persist them across restarts. Each file holds the expiry, the key and the
+// value of an entry.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache will create and return a new file cache that stores entries in
+// the provided directory. The directory is created if missing.
+func NewFileCache(dir string) (*FileCache, error) {
+	// ensure directory
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileCache{
+		dir: dir,
+	}, nil
+}
+
+// Get implements the Cache interface.
+func (c *FileCache) Get(key string) ([]byte, bool) {
+	// read file
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	// decode entry
+	expires, storedKey, value, ok := decodeFileEntry(data)
+	if !ok || storedKey != key {
+		return nil, false
+	}
+
+	// check expiry
+	if time.Now().After(expires) {
+		_ = os.Remove(c.path(key))
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set implements the Cache interface.
+func (c *FileCache) Set(key string, value []byte, ttl time.Duration) {
+	// prepare data
+	data := make([]byte, 12+len(key)+len(value))
+	binary.BigEndian.PutUint64(data, uint64(time.Now().Add(ttl).UnixNano()))
+	binary.BigEndian.PutUint32(data[8:], uint32(len(key)))
+	copy(data[12:], key)
+	copy(data[12+len(key):], value)
+
+	// write to temporary file
+	file, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = file.Write(data)
+	if err2 := file.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return
+	}
+
+	// move file into place
+	err = os.Rename(file.Name(), c.path(key))
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+}
+
+// Delete implements the Cache interface.
+func (c *FileCache) Delete(key string) {
+	_ = os.Remove(c.path(key))
+}
+
+// Keys implements the CacheScanner interface.
+func (c *FileCache) Keys() []string {
+	// list files
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil
+	}
+
+	// read keys
+	now := time.Now()
+	var keys []string
+	for _, entry := range entries {
+		// skip directories and temporary files
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			continue
+		}
+
+		// read file
+		data, err := os.ReadFile(filepath.Join(c.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		// decode entry
+		expires, key, _, ok := decodeFileEntry(data)
+		if ok && !now.After(expires) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Clean implements the CacheCleaner interface.
+func (c *FileCache) Clean() error {
+	// list files
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	// check files
+	for _, entry := range entries {
+		// skip directories and temporary files
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			continue
+		}
+
+		// read expiry
+		path := filepath.Join(c.dir, entry.Name())
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		var buf [8]byte
+		_, err = io.ReadFull(file, buf[:])
+		_ = file.Close()
+
+		// remove invalid and expired files
+		if err != nil || time.Now().After(time.Unix(0, int64(binary.BigEndian.Uint64(buf[:])))) {
+			err = os.Remove(path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func decodeFileEntry(data []byte) (time.Time, string, []byte, bool) {
+	// check header
+	if len(data) < 12 {
+		return time.Time{}, "", nil, false
+	}
+
+	// check key
+	size := int(binary.BigEndian.Uint32(data[8:]))
+	if len(data) < 12+size {
+		return time.Time{}, "", nil, false
+	}
+
+	// get fields
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	key := string(data[12 : 12+size])
+	value := data[12+size:]
+
+	return expires, key, value, true
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
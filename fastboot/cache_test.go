@@ -0,0 +1,116 @@
+package fastboot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember/example"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(10)
+
+	cache.Set("a", []byte("1234"), time.Minute)
+	cache.Set("b", []byte("1234"), time.Minute)
+	assert.Equal(t, int64(10), cache.Size())
+
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), value)
+
+	cache.Set("c", []byte("1234"), time.Minute)
+	assert.Equal(t, int64(10), cache.Size())
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	cache.Set("d", []byte("1234567890"), time.Minute)
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, int64(5), cache.Size())
+
+	cache.Set("e", []byte("1"), -time.Second)
+	_, ok = cache.Get("e")
+	assert.False(t, ok)
+	assert.Equal(t, int64(5), cache.Size())
+	assert.Equal(t, []string{"c"}, cache.Keys())
+}
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir)
+	assert.NoError(t, err)
+
+	cache.Set("a", []byte("1234"), time.Minute)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), value)
+
+	cache, err = NewFileCache(dir)
+	assert.NoError(t, err)
+
+	value, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), value)
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+
+	cache.Set("b", []byte("1234"), -time.Second)
+	cache.Set("c", []byte("1234"), time.Minute)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+
+	cache.Set("b", []byte("1234"), -time.Second)
+	err = cache.Clean()
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	_, err = os.Stat(cache.path("c"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, cache.Keys())
+}
+
+func TestHandlerCacheClean(t *testing.T) {
+	dir := t.TempDir()
+
+	cache, err := NewFileCache(dir)
+	assert.NoError(t, err)
+
+	handler, err := Handle(Options{
+		App:        example.App(),
+		Origin:     "https://example.org",
+		Cache:      10 * time.Millisecond,
+		CacheStore: cache,
+		Isolated:   true,
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	cache.Set("a", []byte("1234"), time.Millisecond)
+	cache.Set("b", []byte("1234"), time.Minute)
+
+	assert.Eventually(t, func() bool {
+		files, err := filepath.Glob(filepath.Join(dir, "*"))
+		return err == nil && len(files) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	_, ok := cache.Get("b")
+	assert.True(t, ok)
+}
@@ -0,0 +1,145 @@
+package fastboot
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/256dpi/ember"
+)
+
+// ExportOptions are used to configure an export.
+type ExportOptions struct {
+	App     *ember.App
+	Origin  string
+	Routes  []string
+	Dir     string
+	Timeout time.Duration // 5s
+	Headed  bool
+	Shell   string // "_empty.html"
+	OnRoute func(route string, err error)
+}
+
+// Export will render the configured routes of the app and write them as
+// "route/index.html" files together with all other app files to the
+// configured directory. As rendering "/" replaces the original "index.html",
+// the unrendered index is also written to the shell file, which static hosts
+// should serve for routes that have not been exported. Render failures are
+// returned per route and do not abort the export.
+func Export(options ExportOptions) (map[string]error, error) {
+	// ensure timeout
+	if options.Timeout == 0 {
+		options.Timeout = 5 * time.Second
+	}
+
+	// ensure shell
+	if options.Shell == "" {
+		options.Shell = "_empty.html"
+	}
+
+	// write files
+	for _, name := range options.App.Files() {
+		err := writeFile(options.Dir, name, options.App.File(name))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// get index
+	index := options.App.File("index.html")
+
+	// write shell
+	err := writeFile(options.Dir, options.Shell, index)
+	if err != nil {
+		return nil, err
+	}
+
+	// boot instance
+	instance, err := Boot(options.App, options.Origin, options.Headed)
+	if err != nil {
+		return nil, err
+	}
+	defer instance.Close()
+
+	// render routes
+	failures := map[string]error{}
+	for _, route := range options.Routes {
+		// render route
+		err := exportRoute(instance, options, index, route)
+		if err != nil {
+			failures[route] = err
+		}
+
+		// call callback
+		if options.OnRoute != nil {
+			options.OnRoute(route, err)
+		}
+	}
+
+	return failures, nil
+}
+
+func exportRoute(instance *Instance, options ExportOptions, index []byte, route string) error {
+	// parse route
+	routeURL, err := url.Parse(route)
+	if err != nil {
+		return err
+	}
+
+	// clean path
+	pth := path.Clean("/" + routeURL.Path)
+	routeURL.Path = pth
+
+	// prepare query params
+	queryParams := map[string]string{}
+	for key, values := range routeURL.Query() {
+		queryParams[key] = values[0]
+	}
+
+	// determine protocol and host
+	var protocol, host string
+	originURL, err := url.Parse(options.Origin)
+	if err == nil {
+		protocol = originURL.Scheme + ":"
+		host = originURL.Host
+	}
+
+	// visit route
+	result, err := instance.Visit(routeURL.String(), Request{
+		Method:   "GET",
+		Protocol: protocol,
+		Path:     pth,
+		Headers: map[string][]string{
+			"Host": {host},
+		},
+		Cookies:     map[string]string{},
+		QueryParams: queryParams,
+	}, options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	// write page
+	return writeFile(options.Dir, path.Join(strings.TrimPrefix(pth, "/"), "index.html"), result.Merge(index))
+}
+
+func writeFile(dir, name string, data []byte) error {
+	// check name
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return fmt.Errorf("invalid file name")
+	}
+
+	// ensure directory
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0644)
+}
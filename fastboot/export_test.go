@@ -0,0 +1,42 @@
+package fastboot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember/example"
+)
+
+func TestExport(t *testing.T) {
+	app := example.App()
+	dir := t.TempDir()
+
+	failures, err := Export(ExportOptions{
+		App:    app,
+		Origin: "https://example.org",
+		Routes: []string{"/", "/delay?timeout=100", "/fetch"},
+		Dir:    dir,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(index), `<h1>Example</h1>`)
+	assert.Contains(t, string(index), `<p>Is FastBoot: true</p>`)
+
+	shell, err := os.ReadFile(filepath.Join(dir, "_empty.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, app.File("index.html"), shell)
+
+	delay, err := os.ReadFile(filepath.Join(dir, "delay", "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(delay), `<p>Message: Hello world!</p>`)
+
+	pkg, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, app.File("package.json"), pkg)
+}
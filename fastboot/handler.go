@@ -2,33 +2,124 @@ package fastboot
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	gocache "github.com/patrickmn/go-cache"
-
 	"github.com/256dpi/ember"
 )
 
+// DefaultResponseHeaders are the response headers that are written from
+// renders if no other headers are configured. The "Set-Cookie" header is
+// always written.
+var DefaultResponseHeaders = []string{
+	"Cache-Control",
+	"Content-Language",
+	"Content-Security-Policy",
+	"Expires",
+	"Link",
+	"Vary",
+	"X-Robots-Tag",
+}
+
 // Options are used to configure the handler.
 type Options struct {
-	App       *ember.App
-	Origin    string
-	Timeout   time.Duration // 5s
-	Cache     time.Duration
-	Isolated  bool
-	Headed    bool
-	OnRequest func(*Request)
-	OnResult  func(*Result)
-	OnError   func(error)
+	App             *ember.App
+	Origin          string
+	Timeout         time.Duration // 5s
+	Cache           time.Duration
+	CacheKey        func(*http.Request) (string, bool) // DefaultCacheKey
+	CacheStore      Cache                              // NewMemoryCache(DefaultCacheSize)
+	CacheStale      time.Duration
+	WarmURLs        []string
+	WarmSitemap     string
+	PurgePath       string
+	PurgeToken      string
+	Isolated        bool
+	Headed          bool
+	PoolSize        int           // 1
+	PoolWait        time.Duration // Timeout
+	AllowHeaders    []string      // all
+	DenyHeaders     []string
+	ResponseHeaders []string // DefaultResponseHeaders
+	Metrics         ember.Metrics
+	Logger          *slog.Logger
+	Metadata        func(*http.Request) map[string]interface{}
+	OnRequest       func(*Request)
+	OnResult        func(*Result)
+	OnError         func(error)
+	OnWarm          func(url string, done, total int, err error)
 }
 
 // Handler is a http.Handler that will pre-render the given ember app.
 type Handler struct {
-	options  Options
-	cache    *gocache.Cache
-	instance *Instance
+	options      Options
+	cache        Cache
+	pool         *Pool
+	revalidating map[string]bool
+	purges       purges
+	purging      sync.Mutex
+	active       sync.WaitGroup
+	closed       bool
+	done         chan struct{}
+	stop         sync.Once
+	mutex        sync.Mutex
+}
+
+type page struct {
+	status  int
+	headers http.Header
+	body    []byte
+	created time.Time
+	tags    []string
+}
+
+type pageData struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    []byte      `json:"body"`
+	Created time.Time   `json:"created"`
+	Tags    []string    `json:"tags,omitempty"`
+}
+
+func (p *page) encode() []byte {
+	data, _ := json.Marshal(pageData{
+		Status:  p.status,
+		Headers: p.headers,
+		Body:    p.body,
+		Created: p.created,
+		Tags:    p.tags,
+	})
+	return data
+}
+
+func decodePage(data []byte) (*page, bool) {
+	var pd pageData
+	err := json.Unmarshal(data, &pd)
+	if err != nil {
+		return nil, false
+	}
+	if pd.Headers == nil {
+		pd.Headers = http.Header{}
+	}
+	return &page{
+		status:  pd.Status,
+		headers: pd.Headers,
+		body:    pd.Body,
+		created: pd.Created,
+		tags:    pd.Tags,
+	}, true
+}
+
+type visitor interface {
+	Visit(url string, r Request, timeout time.Duration) (Result, error)
 }
 
 // Handle will create a new handler.
@@ -38,34 +129,109 @@ func Handle(options Options) (*Handler, error) {
 		options.Timeout = 5 * time.Second
 	}
 
+	// check purge token
+	if options.PurgePath != "" && options.PurgeToken == "" {
+		return nil, fmt.Errorf("missing purge token")
+	}
+
+	// ensure cache key
+	if options.CacheKey == nil {
+		options.CacheKey = DefaultCacheKey
+	}
+
+	// ensure response headers
+	if options.ResponseHeaders == nil {
+		options.ResponseHeaders = DefaultResponseHeaders
+	}
+
+	// ensure pool
+	if options.PoolSize <= 0 {
+		options.PoolSize = 1
+	}
+	if options.PoolWait == 0 {
+		options.PoolWait = options.Timeout
+	}
+
 	// prepare cache
-	var cache *gocache.Cache
+	var cache Cache
 	if options.Cache > 0 {
-		cache = gocache.New(options.Cache, options.Cache/4)
+		cache = options.CacheStore
+		if cache == nil {
+			cache = NewMemoryCache(DefaultCacheSize)
+		}
+	}
+
+	// get warm URLs
+	var warm []string
+	if cache != nil {
+		var err error
+		warm, err = warmURLs(options)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// create instance
-	var instance *Instance
+	// create pool
+	var pool *Pool
 	if !options.Isolated {
 		var err error
-		instance, err = Boot(options.App, options.Origin, options.Headed)
+		pool, err = newPool(options.App, options.Origin, options.Headed, options.PoolSize, options.PoolWait, func(err error) {
+			if err != nil {
+				count(options.Metrics, "fastboot_boots_total", "failure")
+			} else {
+				count(options.Metrics, "fastboot_boots_total", "success")
+			}
+		})
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return &Handler{
-		options:  options,
-		cache:    cache,
-		instance: instance,
-	}, nil
+	// create handler
+	handler := &Handler{
+		options:      options,
+		cache:        cache,
+		pool:         pool,
+		revalidating: map[string]bool{},
+		done:         make(chan struct{}),
+	}
+
+	// clean cache in background
+	if cleaner, ok := cache.(CacheCleaner); ok {
+		go handler.clean(cleaner)
+	}
+
+	// warm cache in background
+	if len(warm) > 0 {
+		go func() {
+			_ = handler.Warm(context.Background(), warm)
+		}()
+	}
+
+	return handler, nil
 }
 
 // ServeHTTP implements the http.Handler interface.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// get start
+	start := time.Now()
+
+	// wrap writer if logging
+	if h.options.Logger != nil {
+		w = &ember.AccessWriter{ResponseWriter: w}
+	}
+
+	// handle purges
+	if h.options.PurgePath != "" && r.URL.Path == h.options.PurgePath {
+		h.servePurge(w, r)
+		h.finish(w, r, "purge", start, nil)
+		return
+	}
+
 	// check method
 	if r.Method != "GET" {
 		http.Error(w, "", http.StatusMethodNotAllowed)
+		h.finish(w, r, "invalid", start, nil)
 		return
 	}
 
@@ -75,6 +241,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// handle static files
 	if h.options.App.File(pth) != nil {
 		h.options.App.ServeHTTP(w, r)
+		h.finish(w, r, "asset", start, nil)
 		return
 	}
 
@@ -83,27 +250,162 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// set content type
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	// serve cached result if possible
+	// determine cache key
+	var cacheKey string
+	var cacheable bool
 	if h.cache != nil {
-		cached, ok := h.cache.Get(pth)
+		cacheKey, cacheable = h.options.CacheKey(r)
+	}
+
+	// serve cached result if possible
+	if cacheable {
+		cached, ok := h.lookup(cacheKey, r)
 		if ok {
-			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(cached.([]byte)))
+			// revalidate stale pages in the background
+			kind := "cached"
+			age := time.Since(cached.created)
+			if h.options.CacheStale > 0 && age > h.options.Cache {
+				kind = "stale"
+				h.revalidate(cacheKey, r)
+			}
+
+			// describe state
+			cached.headers.Set("Age", strconv.Itoa(int(age.Seconds())))
+			h.describe(cached, age)
+
+			// write page
+			h.write(w, r, cached)
+			h.finish(w, r, kind, start, nil)
 			return
 		}
 	}
 
-	// build request
-	request := Request{
-		Method:   "GET",
-		Protocol: r.URL.Scheme,
-		Path:     r.URL.Path,
-		Headers: map[string][]string{
-			"Host": {r.Host},
-		},
-		Cookies:     map[string]string{},
-		QueryParams: map[string]string{},
-		Body:        "",
+	// prepare index
+	index := h.options.App.File("index.html")
+
+	// track render or fall back if shut down
+	if !h.acquire() {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(index))
+		h.finish(w, r, "fallback", start, nil)
+		return
+	}
+	defer h.active.Done()
+
+	// render page
+	pg, result, kind, err := h.render(r)
+	if err != nil {
+		if h.options.OnError != nil {
+			h.options.OnError(err)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(index))
+		h.finish(w, r, "fallback", start, err)
+		return
+	}
+
+	// cache page if possible (pages that set cookies are never shared)
+	if cacheable && pg.headers.Get("Set-Cookie") == "" {
+		h.store(cacheKey, r, result.Headers, pg)
+		h.describe(pg, 0)
+	}
+
+	// write page
+	h.write(w, r, pg)
+
+	// record request
+	h.finish(w, r, kind, start, nil)
+}
+
+// Shutdown will stop rendering new requests, wait for active renders to
+// complete and close all browser instances. If the context is cancelled
+// before all renders completed, the context error is returned and the
+// remaining instances are closed once their render completes or times out.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	// set flag
+	h.mutex.Lock()
+	h.closed = true
+	h.mutex.Unlock()
+
+	// wait for active renders
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+
+	// await completion or cancellation
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// close instance
+	h.Close()
+
+	return err
+}
+
+// Close will close the handler without waiting for active renders.
+func (h *Handler) Close() {
+	// set flag
+	h.mutex.Lock()
+	h.closed = true
+	h.mutex.Unlock()
+
+	// stop background tasks
+	h.stop.Do(func() {
+		if h.done != nil {
+			close(h.done)
+		}
+	})
+
+	// close instance
+	if h.pool != nil {
+		h.pool.Close()
+	}
+}
+
+func (h *Handler) clean(cleaner CacheCleaner) {
+	// prepare ticker (entries expire after the stale period)
+	ticker := time.NewTicker(h.options.Cache + h.options.CacheStale)
+	defer ticker.Stop()
+
+	for {
+		// await tick
+		select {
+		case <-ticker.C:
+		case <-h.done:
+			return
+		}
+
+		// remove expired entries
+		err := cleaner.Clean()
+		if err != nil && h.options.OnError != nil {
+			h.options.OnError(err)
+		}
 	}
+}
+
+func (h *Handler) acquire() bool {
+	// acquire mutex
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// check flag
+	if h.closed {
+		return false
+	}
+
+	// track render
+	h.active.Add(1)
+
+	return true
+}
+
+func (h *Handler) render(r *http.Request) (*page, Result, string, error) {
+	// build request
+	request := h.buildRequest(r)
 
 	// clear URL prefix
 	r.URL.Scheme = ""
@@ -111,22 +413,19 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.URL.Host = ""
 	r.URL.User = nil
 
-	// prepare index
-	index := h.options.App.File("index.html")
-
-	// prepare instance
-	instance := h.instance
-	if instance == nil {
-		var err error
-		instance, err = Boot(h.options.App, h.options.Origin, h.options.Headed)
+	// prepare visitor
+	var visitor visitor
+	if h.pool != nil {
+		visitor = h.pool
+	} else {
+		instance, err := Boot(h.options.App, h.options.Origin, h.options.Headed)
 		if err != nil {
-			if h.options.OnError != nil {
-				h.options.OnError(err)
-			}
-			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(index))
-			return
+			count(h.options.Metrics, "fastboot_boots_total", "failure")
+			return nil, Result{}, "", err
 		}
+		count(h.options.Metrics, "fastboot_boots_total", "success")
 		defer instance.Close()
+		visitor = instance
 	}
 
 	// call request handler
@@ -135,45 +434,345 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// visit URL
-	result, err := instance.Visit(r.URL.String(), request, h.options.Timeout)
+	renderStart := time.Now()
+	result, err := visitor.Visit(r.URL.String(), request, h.options.Timeout)
 	if err != nil {
-		if h.options.OnError != nil {
-			h.options.OnError(err)
-		}
-		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(index))
-		return
+		h.record("fastboot_render", "failure", renderStart)
+		return nil, Result{}, "", err
 	}
+	h.record("fastboot_render", "success", renderStart)
 
 	// call result handler
 	if h.options.OnResult != nil {
 		h.options.OnResult(&result)
 	}
 
-	// apply attributes
-	index = bytes.Replace(index, []byte("<body>"), []byte("<body"+result.BodyAttributesString()+">"), 1)
-	index = bytes.Replace(index, []byte("<head>"), []byte("<head"+result.HeadAttributesString()+">"), 1)
-	index = bytes.Replace(index, []byte("<html>"), []byte("<html"+result.HTMLAttributesString()+">"), 1)
+	// prepare page
+	kind := "rendered"
+	pg := &page{
+		status:  result.StatusCode,
+		headers: responseHeaders(result.Headers, h.options.ResponseHeaders),
+		body:    result.Merge(h.options.App.File("index.html")),
+		created: renderStart,
+		tags:    cacheTags(result.Metadata),
+	}
+
+	// handle redirects
+	if location, status, ok := result.Redirect(r.URL.String()); ok {
+		// prefix transition URLs with the root URL
+		if location == result.URL {
+			rootURL, _ := h.options.App.Get("rootURL").(string)
+			location = strings.TrimRight(rootURL, "/") + location
+		}
 
-	// wrap body with boundary tags
-	body := `<script type="x/boundary" id="fastboot-body-start"></script>` + result.BodyContent + `<script type="x/boundary" id="fastboot-body-end"></script>`
+		// update page
+		kind = "redirect"
+		pg.status = status
+		pg.headers.Set("Location", location)
+		pg.body = nil
+	}
 
-	// replace content
-	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_TITLE -->"), nil, 1)
-	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_HEAD -->"), []byte(result.HeadContent), 1)
-	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_BODY -->"), []byte(body), 1)
+	return pg, result, kind, nil
+}
 
-	// write result
-	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(index))
+func (h *Handler) revalidate(key string, r *http.Request) {
+	// check and mark key
+	h.mutex.Lock()
+	if h.revalidating[key] {
+		h.mutex.Unlock()
+		return
+	}
+	h.revalidating[key] = true
+	h.mutex.Unlock()
 
-	// cache result if possible
-	if h.cache != nil {
-		h.cache.Set(pth, index, h.options.Cache)
+	// prepare unmark
+	unmark := func() {
+		h.mutex.Lock()
+		delete(h.revalidating, key)
+		h.mutex.Unlock()
 	}
+
+	// track render
+	if !h.acquire() {
+		unmark()
+		return
+	}
+
+	// copy request
+	r = r.Clone(context.Background())
+
+	// render in background
+	go func() {
+		defer h.active.Done()
+		defer unmark()
+
+		// render page
+		pg, result, _, err := h.render(r)
+		if err != nil {
+			if h.options.OnError != nil {
+				h.options.OnError(err)
+			}
+			return
+		}
+
+		// update cache
+		if pg.headers.Get("Set-Cookie") == "" {
+			h.store(key, r, result.Headers, pg)
+		}
+	}()
 }
 
-// Close will close the handler.
-func (h *Handler) Close() {
-	if h.instance != nil {
-		h.instance.Close()
+func (h *Handler) describe(pg *page, age time.Duration) {
+	// check mode and existing header
+	if h.options.CacheStale <= 0 || pg.headers.Get("Cache-Control") != "" {
+		return
+	}
+
+	// get remaining fresh time
+	maxAge := h.options.Cache - age.Truncate(time.Second)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	// set header
+	pg.headers.Set("Cache-Control", fmt.Sprintf("max-age=%d, stale-while-revalidate=%d", int(maxAge.Seconds()), int(h.options.CacheStale.Seconds())))
+}
+
+func (h *Handler) lookup(key string, r *http.Request) (*page, bool) {
+	// get vary headers
+	data, ok := h.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var vary []string
+	err := json.Unmarshal(data, &vary)
+	if err != nil {
+		return nil, false
+	}
+
+	// get variant
+	data, ok = h.cache.Get(variantKey(key, vary, r))
+	if !ok {
+		return nil, false
+	}
+
+	// decode page
+	pg, ok := decodePage(data)
+	if !ok || h.purged(key, pg) {
+		return nil, false
+	}
+
+	return pg, true
+}
+
+func (h *Handler) store(key string, r *http.Request, headers map[string][]string, pg *page) {
+	// skip pages purged while rendering
+	if h.purged(key, pg) {
+		return
+	}
+
+	// get vary headers
+	vary, ok := varyHeaders(headers)
+	if !ok {
+		return
+	}
+
+	// store vary headers and variant (until the stale period ends)
+	ttl := h.options.Cache + h.options.CacheStale
+	data, _ := json.Marshal(vary)
+	h.cache.Set(key, data, ttl)
+	h.cache.Set(variantKey(key, vary, r), pg.encode(), ttl)
+}
+
+func (h *Handler) buildRequest(r *http.Request) Request {
+	// determine protocol
+	protocol := "http:"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		proto, _, _ = strings.Cut(proto, ",")
+		protocol = strings.ToLower(strings.TrimSpace(proto)) + ":"
+	} else if r.TLS != nil {
+		protocol = "https:"
+	}
+
+	// collect headers
+	headers := map[string][]string{
+		"Host": {r.Host},
+	}
+	for key, values := range r.Header {
+		if h.forwardHeader(key) {
+			headers[key] = values
+		}
+	}
+
+	// collect cookies
+	cookies := map[string]string{}
+	for _, cookie := range r.Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+
+	// collect query params
+	queryParams := map[string]string{}
+	for key, values := range r.URL.Query() {
+		queryParams[key] = values[0]
+	}
+
+	// get metadata
+	var metadata map[string]interface{}
+	if h.options.Metadata != nil {
+		metadata = h.options.Metadata(r)
 	}
+
+	return Request{
+		Method:      "GET",
+		Protocol:    protocol,
+		Path:        r.URL.Path,
+		Headers:     headers,
+		Cookies:     cookies,
+		QueryParams: queryParams,
+		Body:        "",
+		Metadata:    metadata,
+	}
+}
+
+func (h *Handler) forwardHeader(key string) bool {
+	// check deny list
+	for _, name := range h.options.DenyHeaders {
+		if strings.EqualFold(name, key) {
+			return false
+		}
+	}
+
+	// check allow list
+	if len(h.options.AllowHeaders) == 0 {
+		return true
+	}
+	for _, name := range h.options.AllowHeaders {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, pg *page) {
+	// set headers
+	for key, values := range pg.headers {
+		w.Header()[key] = values
+	}
+
+	// serve regular pages
+	if pg.status == 0 || pg.status == http.StatusOK {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(pg.body))
+		return
+	}
+
+	// otherwise, write status and body
+	w.WriteHeader(pg.status)
+	_, _ = w.Write(pg.body)
+}
+
+func (h *Handler) finish(w http.ResponseWriter, r *http.Request, kind string, start time.Time, err error) {
+	// record request
+	h.record("fastboot_requests", kind, start)
+
+	// log request
+	if h.options.Logger != nil {
+		ember.LogAccess(h.options.Logger, r, w.(*ember.AccessWriter), kind, start, err)
+	}
+}
+
+func (h *Handler) record(name, outcome string, start time.Time) {
+	if h.options.Metrics != nil {
+		h.options.Metrics.Count(name+"_total", outcome)
+		h.options.Metrics.Observe(name+"_seconds", outcome, time.Since(start))
+	}
+}
+
+func count(metrics ember.Metrics, name, outcome string) {
+	if metrics != nil {
+		metrics.Count(name, outcome)
+	}
+}
+
+// DefaultCacheKey will return the request path without leading and trailing
+// slashes followed by the sorted query string as the cache key. Requests that
+// carry a "Cookie" or "Authorization" header may render personalized pages and
+// are therefore not cached.
+func DefaultCacheKey(r *http.Request) (string, bool) {
+	// skip credentialed requests
+	if r.Header.Get("Cookie") != "" || r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+
+	// get path (escaped to keep control characters out of the key)
+	key := strings.Trim(r.URL.EscapedPath(), "/")
+
+	// add query
+	if query := r.URL.Query(); len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+
+	return key, true
+}
+
+func varyHeaders(headers map[string][]string) ([]string, bool) {
+	// collect names
+	var names []string
+	for key, values := range headers {
+		if !strings.EqualFold(key, "Vary") {
+			continue
+		}
+		for _, value := range values {
+			for _, name := range strings.Split(value, ",") {
+				name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+				if name == "*" {
+					return nil, false
+				} else if name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	// sort and deduplicate names
+	sort.Strings(names)
+	result := []string{}
+	for i, name := range names {
+		if i == 0 || names[i-1] != name {
+			result = append(result, name)
+		}
+	}
+
+	return result, true
+}
+
+func variantKey(key string, vary []string, r *http.Request) string {
+	// add header values (the separator keeps the variant and base key apart)
+	key += "\n"
+	for _, name := range vary {
+		key += name + ": " + strings.Join(r.Header.Values(name), ", ") + "\n"
+	}
+
+	return key
+}
+
+func responseHeaders(headers map[string][]string, allowed []string) http.Header {
+	// copy allowed headers
+	result := http.Header{}
+	for key, values := range headers {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Set-Cookie" {
+			result[key] = append([]string{}, values...)
+			continue
+		}
+		for _, name := range allowed {
+			if strings.EqualFold(name, key) {
+				result[key] = append([]string{}, values...)
+				break
+			}
+		}
+	}
+
+	return result
 }
@@ -1,12 +1,21 @@
 package fastboot
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/256dpi/ember"
 	"github.com/256dpi/ember/example"
 )
 
@@ -73,6 +82,220 @@ func TestHandlerIsolated(t *testing.T) {
 	assert.Equal(t, string(app.File("index.html")), rec.Body.String())
 }
 
+func TestHandlerStatus(t *testing.T) {
+	app := example.App()
+
+	handler, err := Handle(Options{
+		App:    app,
+		Origin: "https://example.org",
+		Cache:  time.Minute,
+		OnError: func(err error) {
+			assert.NoError(t, err)
+		},
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	for i := 0; i < 2; i++ {
+		for _, code := range []string{"404", "410"} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "https://example.org/status?code="+code, nil)
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, code, strconv.Itoa(rec.Code))
+			assert.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"))
+			assert.Contains(t, rec.Body.String(), `<p>Status: `+code+`</p>`)
+		}
+	}
+}
+
+func TestHandlerCookies(t *testing.T) {
+	app := example.App()
+
+	var renders int
+	handler, err := Handle(Options{
+		App:    app,
+		Origin: "https://example.org",
+		Cache:  time.Minute,
+		OnResult: func(*Result) {
+			renders++
+		},
+		OnError: func(err error) {
+			assert.NoError(t, err)
+		},
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "https://example.org/status?cookie=foo%3Dbar", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, "foo=bar", rec.Header().Get("Set-Cookie"))
+	}
+
+	assert.Equal(t, 2, renders)
+}
+
+func TestHandlerRedirect(t *testing.T) {
+	app := example.App()
+
+	handler, err := Handle(Options{
+		App:    app,
+		Origin: "https://example.org",
+		OnError: func(err error) {
+			assert.NoError(t, err)
+		},
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "https://example.org/moved", nil)
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, 302, rec.Code)
+	assert.Equal(t, "/status", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestHandlerRequest(t *testing.T) {
+	handler := &Handler{
+		options: Options{
+			DenyHeaders: []string{"authorization"},
+		},
+	}
+
+	req := httptest.NewRequest("GET", "https://example.org/debug?foo=bar&baz=1", nil)
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("Authorization", "secret")
+	req.Header.Set("Cookie", "session=foo; other=bar")
+	req.Header.Set("X-Forwarded-Proto", "https")
+
+	assert.Equal(t, Request{
+		Method:   "GET",
+		Protocol: "https:",
+		Path:     "/debug",
+		Headers: map[string][]string{
+			"Host":              {"example.org"},
+			"Accept-Language":   {"de"},
+			"Cookie":            {"session=foo; other=bar"},
+			"X-Forwarded-Proto": {"https"},
+		},
+		Cookies: map[string]string{
+			"session": "foo",
+			"other":   "bar",
+		},
+		QueryParams: map[string]string{
+			"foo": "bar",
+			"baz": "1",
+		},
+	}, handler.buildRequest(req))
+
+	handler.options.AllowHeaders = []string{"Accept-Language"}
+	req.Header.Del("X-Forwarded-Proto")
+	assert.Equal(t, Request{
+		Method:   "GET",
+		Protocol: "https:",
+		Path:     "/debug",
+		Headers: map[string][]string{
+			"Host":            {"example.org"},
+			"Accept-Language": {"de"},
+		},
+		Cookies: map[string]string{
+			"session": "foo",
+			"other":   "bar",
+		},
+		QueryParams: map[string]string{
+			"foo": "bar",
+			"baz": "1",
+		},
+	}, handler.buildRequest(req))
+
+	handler.options.Metadata = func(r *http.Request) map[string]interface{} {
+		return map[string]interface{}{
+			"user": r.URL.Query().Get("foo"),
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"user": "bar",
+	}, handler.buildRequest(req).Metadata)
+}
+
+func TestResponseHeaders(t *testing.T) {
+	headers := responseHeaders(map[string][]string{
+		"cache-control": {"max-age=60"},
+		"set-cookie":    {"a=1", "b=2"},
+		"x-internal":    {"foo"},
+	}, DefaultResponseHeaders)
+	assert.Equal(t, http.Header{
+		"Cache-Control": {"max-age=60"},
+		"Set-Cookie":    {"a=1", "b=2"},
+	}, headers)
+}
+
+func TestHandlerShutdown(t *testing.T) {
+	app := example.App()
+
+	handler, err := Handle(Options{
+		App:      app,
+		Origin:   "https://example.org",
+		Isolated: true,
+	})
+	assert.NoError(t, err)
+
+	err = handler.Shutdown(context.Background())
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "https://example.org/", nil)
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, string(app.File("index.html")), rec.Body.String())
+
+	handler.Close()
+}
+
+func TestHandlerShutdownRender(t *testing.T) {
+	for _, item := range []struct {
+		delay   string
+		timeout time.Duration
+		err     error
+	}{
+		{delay: "500", timeout: 5 * time.Second},
+		{delay: "2000", timeout: 100 * time.Millisecond, err: context.DeadlineExceeded},
+	} {
+		started := make(chan struct{})
+		handler, err := Handle(Options{
+			App:    example.App(),
+			Origin: "https://example.org",
+			OnRequest: func(*Request) {
+				close(started)
+			},
+		})
+		assert.NoError(t, err)
+
+		done := make(chan string, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "https://example.org/delay?timeout="+item.delay, nil)
+			handler.ServeHTTP(rec, req)
+			done <- rec.Body.String()
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), item.timeout)
+		err = handler.Shutdown(ctx)
+		cancel()
+		assert.Equal(t, item.err, err)
+
+		select {
+		case body := <-done:
+			assert.Contains(t, body, `<p>Message: Hello world!</p>`)
+		case <-time.After(5 * time.Second):
+			t.Error("render did not complete")
+		}
+	}
+}
+
 func BenchmarkHandlerCache(b *testing.B) {
 	app := example.App()
 
@@ -94,3 +317,384 @@ func BenchmarkHandlerCache(b *testing.B) {
 		assert.NotZero(b, rec.Body.Len())
 	}
 }
+
+func TestDefaultCacheKey(t *testing.T) {
+	key, ok := DefaultCacheKey(httptest.NewRequest("GET", "/", nil))
+	assert.True(t, ok)
+	assert.Equal(t, "", key)
+
+	key, ok = DefaultCacheKey(httptest.NewRequest("GET", "/foo/?b=2&a=1", nil))
+	assert.True(t, ok)
+	assert.Equal(t, "foo?a=1&b=2", key)
+
+	req := httptest.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Cookie", "session=foo")
+	_, ok = DefaultCacheKey(req)
+	assert.False(t, ok)
+
+	req = httptest.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Authorization", "Bearer foo")
+	_, ok = DefaultCacheKey(req)
+	assert.False(t, ok)
+}
+
+func TestHandlerCacheCredentials(t *testing.T) {
+	app := example.App()
+
+	handler := &Handler{
+		options: Options{
+			App:      app,
+			Cache:    time.Minute,
+			CacheKey: DefaultCacheKey,
+		},
+		cache:  NewMemoryCache(DefaultCacheSize),
+		closed: true,
+	}
+
+	handler.store("foo", httptest.NewRequest("GET", "/foo", nil), nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("cached"),
+		created: time.Now(),
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/foo", nil))
+	assert.Equal(t, "cached", rec.Body.String())
+
+	for _, header := range []string{"Cookie", "Authorization"} {
+		req := httptest.NewRequest("GET", "/foo", nil)
+		req.Header.Set(header, "secret")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, string(app.File("index.html")), rec.Body.String(), header)
+	}
+}
+
+func TestHandlerCacheVary(t *testing.T) {
+	handler := &Handler{
+		options: Options{Cache: time.Minute},
+		cache:   NewMemoryCache(DefaultCacheSize),
+	}
+
+	en := httptest.NewRequest("GET", "/", nil)
+	en.Header.Set("Accept-Language", "en")
+	de := httptest.NewRequest("GET", "/", nil)
+	de.Header.Set("Accept-Language", "de")
+
+	handler.store("", en, map[string][]string{
+		"vary": {"accept-language"},
+	}, &page{body: []byte("en")})
+
+	pg, ok := handler.lookup("", en)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("en"), pg.body)
+
+	_, ok = handler.lookup("", de)
+	assert.False(t, ok)
+
+	handler.store("", de, map[string][]string{
+		"vary": {"accept-language"},
+	}, &page{body: []byte("de")})
+
+	pg, ok = handler.lookup("", de)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("de"), pg.body)
+
+	handler.store("foo", en, nil, &page{body: []byte("foo")})
+
+	pg, ok = handler.lookup("foo", de)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("foo"), pg.body)
+
+	handler.store("bar", en, map[string][]string{
+		"vary": {"*"},
+	}, &page{body: []byte("bar")})
+
+	_, ok = handler.lookup("bar", en)
+	assert.False(t, ok)
+}
+
+func TestVaryHeaders(t *testing.T) {
+	vary, ok := varyHeaders(map[string][]string{
+		"vary": {"cookie, accept-language", "Cookie"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Accept-Language", "Cookie"}, vary)
+
+	_, ok = varyHeaders(map[string][]string{
+		"vary": {"*"},
+	})
+	assert.False(t, ok)
+}
+
+func TestHandlerCacheStale(t *testing.T) {
+	handler := &Handler{
+		options: Options{
+			App:        example.App(),
+			Cache:      time.Minute,
+			CacheKey:   DefaultCacheKey,
+			CacheStale: time.Hour,
+		},
+		cache:        NewMemoryCache(DefaultCacheSize),
+		revalidating: map[string]bool{},
+		closed:       true,
+	}
+
+	req := httptest.NewRequest("GET", "/foo", nil)
+	handler.store("foo", req, nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("fresh"),
+		created: time.Now().Add(-20 * time.Second),
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "fresh", rec.Body.String())
+	assert.Equal(t, "20", rec.Header().Get("Age"))
+	assert.Equal(t, "max-age=40, stale-while-revalidate=3600", rec.Header().Get("Cache-Control"))
+
+	handler.store("foo", req, nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("stale"),
+		created: time.Now().Add(-2 * time.Minute),
+	})
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "stale", rec.Body.String())
+	assert.Equal(t, "120", rec.Header().Get("Age"))
+	assert.Equal(t, "max-age=0, stale-while-revalidate=3600", rec.Header().Get("Cache-Control"))
+	assert.Empty(t, handler.revalidating)
+
+	handler.store("foo", req, nil, &page{
+		status: 200,
+		headers: http.Header{
+			"Cache-Control": {"no-cache"},
+		},
+		body:    []byte("custom"),
+		created: time.Now(),
+	})
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "custom", rec.Body.String())
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	collector := ember.NewCollector()
+
+	pool := &Pool{
+		free: make(chan *Instance),
+		done: make(chan struct{}),
+	}
+	close(pool.done)
+
+	handler := &Handler{
+		options: Options{
+			App:      example.App(),
+			Cache:    time.Minute,
+			CacheKey: DefaultCacheKey,
+			Metrics:  collector,
+		},
+		cache:        NewMemoryCache(DefaultCacheSize),
+		pool:         pool,
+		revalidating: map[string]bool{},
+	}
+
+	req := httptest.NewRequest("GET", "/foo", nil)
+	handler.store("foo", req, nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("cached"),
+		created: time.Now(),
+	})
+
+	for _, pth := range []string{"/foo", "/foo", "/bar"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", pth, nil))
+		assert.Equal(t, 200, rec.Code)
+	}
+
+	handler.closed = true
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/baz", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	handler.PurgeAll()
+
+	snapshot := collector.Snapshot()
+	assert.Equal(t, map[string]interface{}{
+		"cached":   uint64(2),
+		"fallback": uint64(2),
+	}, snapshot["fastboot_requests_total"])
+	assert.Len(t, snapshot["fastboot_requests_seconds"], 2)
+	assert.Equal(t, map[string]interface{}{
+		"failure": uint64(1),
+	}, snapshot["fastboot_render_total"])
+	assert.Equal(t, map[string]interface{}{
+		"all": uint64(1),
+	}, snapshot["fastboot_purges_total"])
+	assert.Nil(t, snapshot["fastboot_boots_total"])
+}
+
+func TestHandlerMetricsRender(t *testing.T) {
+	collector := ember.NewCollector()
+
+	handler, err := Handle(Options{
+		App:     example.App(),
+		Origin:  "https://example.org",
+		Metrics: collector,
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "https://example.org/", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	snapshot := collector.Snapshot()
+	assert.Equal(t, map[string]interface{}{
+		"success": uint64(1),
+	}, snapshot["fastboot_boots_total"])
+	assert.Equal(t, map[string]interface{}{
+		"success": uint64(1),
+	}, snapshot["fastboot_render_total"])
+	assert.Equal(t, map[string]interface{}{
+		"rendered": uint64(1),
+	}, snapshot["fastboot_requests_total"])
+}
+
+func TestHandlerLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	pool := &Pool{
+		free: make(chan *Instance),
+		done: make(chan struct{}),
+	}
+	close(pool.done)
+
+	handler := &Handler{
+		options: Options{
+			App:        example.App(),
+			Cache:      time.Minute,
+			CacheKey:   DefaultCacheKey,
+			CacheStale: time.Hour,
+			Logger:     logger,
+		},
+		cache:        NewMemoryCache(DefaultCacheSize),
+		pool:         pool,
+		revalidating: map[string]bool{},
+	}
+
+	handler.store("foo", httptest.NewRequest("GET", "/foo", nil), nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("fresh"),
+		created: time.Now(),
+	})
+	handler.store("stale", httptest.NewRequest("GET", "/stale", nil), nil, &page{
+		status:  200,
+		headers: http.Header{},
+		body:    []byte("stale"),
+		created: time.Now().Add(-2 * time.Minute),
+	})
+
+	for _, pth := range []string{"/foo", "/bar"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", pth, nil))
+	}
+
+	handler.closed = true
+	for _, pth := range []string{"/stale", "/baz"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", pth, nil))
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		err := json.Unmarshal([]byte(line), &entry)
+		assert.NoError(t, err)
+		entries = append(entries, map[string]interface{}{
+			"level": entry["level"],
+			"path":  entry["path"],
+			"kind":  entry["kind"],
+			"error": entry["error"],
+		})
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "INFO", "path": "/foo", "kind": "cached", "error": nil},
+		{"level": "WARN", "path": "/bar", "kind": "fallback", "error": ErrPoolClosed.Error()},
+		{"level": "INFO", "path": "/stale", "kind": "stale", "error": nil},
+		{"level": "INFO", "path": "/baz", "kind": "fallback", "error": nil},
+	}, entries)
+}
+
+func TestHandlerLoggerRender(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler, err := Handle(Options{
+		App:    example.App(),
+		Origin: "https://example.org",
+		Logger: logger,
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "https://example.org/", nil))
+	assert.Equal(t, 200, rec.Code)
+
+	var entry map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "rendered", entry["kind"])
+	assert.Equal(t, float64(rec.Body.Len()), entry["bytes"])
+}
+
+func TestHandlerRevalidate(t *testing.T) {
+	app := example.App()
+
+	var renders int32
+	handler, err := Handle(Options{
+		App:        app,
+		Origin:     "https://example.org",
+		Cache:      100 * time.Millisecond,
+		CacheStale: time.Minute,
+		OnResult: func(*Result) {
+			atomic.AddInt32(&renders, 1)
+		},
+		OnError: func(err error) {
+			assert.NoError(t, err)
+		},
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "https://example.org/", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&renders))
+
+	time.Sleep(200 * time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "https://example.org/", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<h1>Example</h1>")
+	assert.Equal(t, "max-age=0, stale-while-revalidate=60", rec.Header().Get("Cache-Control"))
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&renders) == 2
+	}, timeout, 10*time.Millisecond)
+}
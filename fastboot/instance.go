@@ -1,13 +1,18 @@
 package fastboot
 
 import (
+	"bytes"
 	"context"
 	_ "embed" // for embedding
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"net/http"
 	"net/url"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +30,14 @@ var script string
 
 var noSandbox = false
 
+var shoeboxEscaper = strings.NewReplacer(
+	"&", `\u0026`,
+	">", `\u003e`,
+	"<", `\u003c`,
+	"\u2028", `\u2028`,
+	"\u2029", `\u2029`,
+)
+
 type manifest struct {
 	Fastboot struct {
 		Manifest struct {
@@ -34,24 +47,52 @@ type manifest struct {
 	} `json:"fastboot"`
 }
 
-// Request represents a request to be made.
+// Request represents a request to be made. The metadata is made available to
+// the app as `fastboot.metadata`.
 type Request struct {
-	Method      string              `json:"method"`
-	Protocol    string              `json:"protocol"`
-	Path        string              `json:"path"`
-	Headers     map[string][]string `json:"headers"`
-	Cookies     map[string]string   `json:"cookies"`
-	QueryParams map[string]string   `json:"queryParams"`
-	Body        string              `json:"body"`
+	Method      string                 `json:"method"`
+	Protocol    string                 `json:"protocol"`
+	Path        string                 `json:"path"`
+	Headers     map[string][]string    `json:"headers"`
+	Cookies     map[string]string      `json:"cookies"`
+	QueryParams map[string]string      `json:"queryParams"`
+	Body        string                 `json:"body"`
+	Metadata    map[string]interface{} `json:"metadata"`
 }
 
 // Result represents the result of an instance visit.
 type Result struct {
-	HeadContent    string            `json:"headContent"`
-	BodyContent    string            `json:"bodyContent"`
-	HTMLAttributes map[string]string `json:"htmlAttributes"`
-	HeadAttributes map[string]string `json:"headAttributes"`
-	BodyAttributes map[string]string `json:"bodyAttributes"`
+	HeadContent    string                     `json:"headContent"`
+	BodyContent    string                     `json:"bodyContent"`
+	HTMLAttributes map[string]string          `json:"htmlAttributes"`
+	HeadAttributes map[string]string          `json:"headAttributes"`
+	BodyAttributes map[string]string          `json:"bodyAttributes"`
+	StatusCode     int                        `json:"statusCode"`
+	Headers        map[string][]string        `json:"headers"`
+	URL            string                     `json:"url"`
+	Shoebox        map[string]json.RawMessage `json:"shoebox"`
+	Metadata       map[string]interface{}     `json:"metadata"`
+}
+
+// Redirect will return the location and status code of a redirect if the app
+// either responded with a 3xx status code and a location header or the
+// final URL of the render differs from the visited URL.
+func (r *Result) Redirect(visited string) (string, int, bool) {
+	// check status code
+	if r.StatusCode >= 300 && r.StatusCode < 400 {
+		for key, values := range r.Headers {
+			if strings.EqualFold(key, "location") && len(values) > 0 {
+				return values[0], r.StatusCode, true
+			}
+		}
+	}
+
+	// check final URL
+	if r.URL != "" && cleanPath(r.URL) != cleanPath(visited) {
+		return r.URL, http.StatusFound, true
+	}
+
+	return "", 0, false
 }
 
 // HTMLAttributesString will return the HTML attributes as a string.
@@ -85,10 +126,49 @@ func (r *Result) HTML() string {
 		r.HeadAttributesString(),
 		r.HeadContent,
 		r.BodyAttributesString(),
-		r.BodyContent,
+		r.BodyContent+r.ShoeboxString(),
 	)
 }
 
+// ShoeboxString will return the shoebox entries as script tags in the same
+// format as ember-cli-fastboot.
+func (r *Result) ShoeboxString() string {
+	// sort keys
+	keys := make([]string, 0, len(r.Shoebox))
+	for key := range r.Shoebox {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// build tags
+	var result string
+	for _, key := range keys {
+		value := shoeboxEscaper.Replace(string(r.Shoebox[key]))
+		result += fmt.Sprintf(`<script type="fastboot/shoebox" id="shoebox-%s">%s</script>`, html.EscapeString(key), value)
+	}
+
+	return result
+}
+
+// Merge will merge the result into the provided index HTML file and return
+// the updated copy.
+func (r *Result) Merge(index []byte) []byte {
+	// apply attributes
+	index = bytes.Replace(index, []byte("<body>"), []byte("<body"+r.BodyAttributesString()+">"), 1)
+	index = bytes.Replace(index, []byte("<head>"), []byte("<head"+r.HeadAttributesString()+">"), 1)
+	index = bytes.Replace(index, []byte("<html>"), []byte("<html"+r.HTMLAttributesString()+">"), 1)
+
+	// wrap body and shoebox with boundary tags
+	body := `<script type="x/boundary" id="fastboot-body-start"></script>` + r.BodyContent + r.ShoeboxString() + `<script type="x/boundary" id="fastboot-body-end"></script>`
+
+	// replace content
+	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_TITLE -->"), nil, 1)
+	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_HEAD -->"), []byte(r.HeadContent), 1)
+	index = bytes.Replace(index, []byte("<!-- EMBER_CLI_FASTBOOT_BODY -->"), []byte(body), 1)
+
+	return index
+}
+
 // Instance represents a running Fastboot instance.
 type Instance struct {
 	app    *ember.App
@@ -97,6 +177,7 @@ type Instance struct {
 	ctx    context.Context
 	cancel func()
 	errs   []error
+	pooled bool
 	mutex  sync.Mutex
 }
 
@@ -268,8 +349,8 @@ func (i *Instance) Visit(url string, r Request, timeout time.Duration) (Result,
 	// run actions
 	err = chromedp.Run(ctx, actions...)
 	if err != nil {
-		// reboot instance on timeout
-		if errors.Is(err, context.DeadlineExceeded) {
+		// reboot instance on timeout (pooled instances are replaced instead)
+		if errors.Is(err, context.DeadlineExceeded) && !i.pooled {
 			_ = i.boot()
 		}
 
@@ -295,12 +376,34 @@ func (i *Instance) Visit(url string, r Request, timeout time.Duration) (Result,
 	return result, nil
 }
 
+// broken returns whether the instance cannot be reused after a visit failed
+// with the provided error. This is the case if the visit timed out or the
+// browser is gone, but not if the app merely logged errors.
+func (i *Instance) broken(err error) bool {
+	// check error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	// acquire mutex
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	// check browser
+	return i.ctx == nil || i.ctx.Err() != nil
+}
+
 // Close will close the instance and release all resources.
 func (i *Instance) Close() {
 	// acquire mutex
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
+	// check context
+	if i.cancel == nil {
+		return
+	}
+
 	// cancel context
 	i.cancel()
 
@@ -343,6 +446,12 @@ func Render(app *ember.App, location string, r Request, timeout time.Duration) (
 	return result, nil
 }
 
+func cleanPath(location string) string {
+	location, _, _ = strings.Cut(location, "?")
+	location, _, _ = strings.Cut(location, "#")
+	return path.Clean("/" + location)
+}
+
 func attributesString(attrs map[string]string) string {
 	var result string
 	for name, value := range attrs {
@@ -30,6 +30,8 @@ func TestRender(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, result.HTML(), "<h1>Example</h1>")
 	assert.Contains(t, result.HTML(), "<p>Name: Joël Gähwiler</p>")
+	assert.Contains(t, result.HTML(), `<script type="fastboot/shoebox" id="shoebox-user">`)
+	assert.Contains(t, string(result.Shoebox["user"]), `"login":"256dpi"`)
 }
 
 func TestRenderResult(t *testing.T) {
@@ -53,6 +55,11 @@ func TestRenderResult(t *testing.T) {
 		BodyAttributes: map[string]string{
 			"foo": "body",
 		},
+		StatusCode: 200,
+		Headers:    map[string][]string{},
+		URL:        "/?attributes=1",
+		Shoebox:    map[string]json.RawMessage{},
+		Metadata:   map[string]interface{}{},
 	}, result)
 
 	result, err = Render(app, "https://example.org/", Request{Path: "/"}, timeout)
@@ -63,9 +70,51 @@ func TestRenderResult(t *testing.T) {
 		HTMLAttributes: map[string]string{},
 		HeadAttributes: map[string]string{},
 		BodyAttributes: map[string]string{},
+		StatusCode:     200,
+		Headers:        map[string][]string{},
+		URL:            "/",
+		Shoebox:        map[string]json.RawMessage{},
+		Metadata:       map[string]interface{}{},
 	}, result)
 }
 
+func TestResultRedirect(t *testing.T) {
+	result := Result{StatusCode: 200, URL: "/foo?bar=baz"}
+	_, _, ok := result.Redirect("/foo/?bar=baz")
+	assert.False(t, ok)
+
+	result = Result{StatusCode: 200, URL: "/login"}
+	location, status, ok := result.Redirect("/foo")
+	assert.True(t, ok)
+	assert.Equal(t, "/login", location)
+	assert.Equal(t, 302, status)
+
+	result = Result{StatusCode: 301, URL: "/foo", Headers: map[string][]string{
+		"location": {"https://example.org/bar"},
+	}}
+	location, status, ok = result.Redirect("/foo")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.org/bar", location)
+	assert.Equal(t, 301, status)
+}
+
+func TestResultShoebox(t *testing.T) {
+	result := Result{Shoebox: map[string]json.RawMessage{
+		"foo": json.RawMessage(`{"html":"</script><script>alert(1)</script>","amp":"&"}`),
+		"bar": json.RawMessage("\"line\u2028break\""),
+	}}
+	assert.Equal(t, `<script type="fastboot/shoebox" id="shoebox-bar">"line\u2028break"</script>`+
+		`<script type="fastboot/shoebox" id="shoebox-foo">{"html":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e","amp":"\u0026"}</script>`,
+		result.ShoeboxString())
+
+	index := []byte(`<body><!-- EMBER_CLI_FASTBOOT_BODY --></body>`)
+	assert.Equal(t, `<body><script type="x/boundary" id="fastboot-body-start"></script>`+
+		`<p>Hello</p><script type="fastboot/shoebox" id="shoebox-bar">"line\u2028break"</script>`+
+		`<script type="fastboot/shoebox" id="shoebox-foo">{"html":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e","amp":"\u0026"}</script>`+
+		`<script type="x/boundary" id="fastboot-body-end"></script></body>`,
+		string((&Result{BodyContent: "<p>Hello</p>", Shoebox: result.Shoebox}).Merge(index)))
+}
+
 func TestRenderDebug(t *testing.T) {
 	app := example.App()
 
@@ -84,8 +133,14 @@ func TestRenderDebug(t *testing.T) {
 			"bar": "baz",
 		},
 		Body: "quz",
+		Metadata: map[string]interface{}{
+			"user": "joel",
+		},
 	}, timeout)
 	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"user": "joel",
+	}, result.Metadata)
 
 	_, raw, _ := strings.Cut(result.BodyContent, "</h1>")
 	var out map[string]interface{}
@@ -118,7 +173,9 @@ func TestRenderDebug(t *testing.T) {
 				"headers": map[string]interface{}{},
 			},
 		},
-		"metadata": map[string]interface{}{},
+		"metadata": map[string]interface{}{
+			"user": "joel",
+		},
 	}, out)
 }
 
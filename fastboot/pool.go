@@ -0,0 +1,181 @@
+package fastboot
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/256dpi/ember"
+)
+
+// ErrPoolTimeout is returned if no instance became available in time.
+var ErrPoolTimeout = errors.New("pool timeout")
+
+// ErrPoolClosed is returned if the pool has been closed.
+var ErrPoolClosed = errors.New("pool closed")
+
+// Pool manages multiple instances to render visits concurrently. Instances
+// that fail or time out are replaced in the background.
+type Pool struct {
+	app    *ember.App
+	origin string
+	headed bool
+	wait   time.Duration
+	onBoot func(error)
+	free   chan *Instance
+	done   chan struct{}
+	closed bool
+	mutex  sync.Mutex
+}
+
+// NewPool will boot the specified number of instances and return a pool that
+// dispatches visits to free instances. Visits wait up to the specified
+// duration for a free instance, a zero wait blocks until one is available.
+func NewPool(app *ember.App, origin string, headed bool, size int, wait time.Duration) (*Pool, error) {
+	return newPool(app, origin, headed, size, wait, nil)
+}
+
+func newPool(app *ember.App, origin string, headed bool, size int, wait time.Duration, onBoot func(error)) (*Pool, error) {
+	// ensure size
+	if size <= 0 {
+		size = 1
+	}
+
+	// prepare pool
+	pool := &Pool{
+		app:    app,
+		origin: origin,
+		headed: headed,
+		wait:   wait,
+		onBoot: onBoot,
+		free:   make(chan *Instance, size),
+		done:   make(chan struct{}),
+	}
+
+	// boot instances
+	for i := 0; i < size; i++ {
+		instance, err := pool.boot()
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		pool.free <- instance
+	}
+
+	return pool, nil
+}
+
+// Visit will visit the provided URL using a free instance and return the
+// result.
+func (p *Pool) Visit(url string, r Request, timeout time.Duration) (Result, error) {
+	// prepare timer
+	var expired <-chan time.Time
+	if p.wait > 0 {
+		timer := time.NewTimer(p.wait)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	// acquire instance
+	var instance *Instance
+	select {
+	case instance = <-p.free:
+	case <-expired:
+		return Result{}, ErrPoolTimeout
+	case <-p.done:
+		return Result{}, ErrPoolClosed
+	}
+
+	// visit URL
+	result, err := instance.Visit(url, r, timeout)
+	if err != nil {
+		// replace broken instances, otherwise keep using the instance
+		if instance.broken(err) {
+			go p.replace(instance)
+		} else {
+			p.release(instance)
+		}
+		return Result{}, err
+	}
+
+	// release instance
+	p.release(instance)
+
+	return result, nil
+}
+
+// Close will close the pool and all free instances. Instances that are in
+// use are closed once their visit completes.
+func (p *Pool) Close() {
+	// acquire mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// check flag
+	if p.closed {
+		return
+	}
+
+	// set flag
+	p.closed = true
+	close(p.done)
+
+	// close free instances
+	for {
+		select {
+		case instance := <-p.free:
+			instance.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) boot() (*Instance, error) {
+	// boot instance
+	instance, err := Boot(p.app, p.origin, p.headed)
+	if p.onBoot != nil {
+		p.onBoot(err)
+	}
+	if instance != nil {
+		instance.pooled = true
+	}
+
+	return instance, err
+}
+
+func (p *Pool) release(instance *Instance) {
+	// acquire mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// close instance if closed
+	if p.closed {
+		instance.Close()
+		return
+	}
+
+	// return instance
+	p.free <- instance
+}
+
+func (p *Pool) replace(instance *Instance) {
+	// close instance
+	instance.Close()
+
+	// boot new instance until successful or closed
+	for {
+		replacement, err := p.boot()
+		if err == nil {
+			p.release(replacement)
+			return
+		}
+
+		// wait before retrying
+		select {
+		case <-time.After(time.Second):
+		case <-p.done:
+			return
+		}
+	}
+}
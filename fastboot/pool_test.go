@@ -0,0 +1,84 @@
+package fastboot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember/example"
+)
+
+func TestPool(t *testing.T) {
+	app := example.App()
+
+	pool, err := NewPool(app, "https://example.org", false, 2, 0)
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := pool.Visit("/delay?timeout=1000", Request{Path: "/delay"}, timeout)
+			assert.NoError(t, err)
+			assert.Contains(t, result.HTML(), "<p>Message: Hello world!</p>")
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, time.Since(start) < 2*time.Second)
+}
+
+func TestPoolWait(t *testing.T) {
+	app := example.App()
+
+	pool, err := NewPool(app, "https://example.org", false, 1, 100*time.Millisecond)
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	go func() {
+		_, _ = pool.Visit("/delay?timeout=1000", Request{Path: "/delay"}, timeout)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = pool.Visit("/", Request{Path: "/"}, timeout)
+	assert.Equal(t, ErrPoolTimeout, err)
+}
+
+func TestPoolReplace(t *testing.T) {
+	app := example.App()
+
+	pool, err := NewPool(app, "https://example.org", false, 1, 0)
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Visit("/delay?timeout=5000", Request{Path: "/delay"}, time.Second)
+	assert.Error(t, err)
+
+	result, err := pool.Visit("/", Request{Path: "/"}, timeout)
+	assert.NoError(t, err)
+	assert.Contains(t, result.HTML(), "<h1>Example</h1>")
+}
+
+func TestInstanceBroken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	instance := &Instance{ctx: ctx}
+
+	assert.False(t, instance.broken(fmt.Errorf("failed to visit URL: %w", errors.New("console error"))))
+	assert.True(t, instance.broken(fmt.Errorf("failed to visit URL: %w", context.DeadlineExceeded)))
+	assert.True(t, instance.broken(fmt.Errorf("failed to visit URL: %w", context.Canceled)))
+
+	cancel()
+	assert.True(t, instance.broken(errors.New("console error")))
+
+	instance = &Instance{}
+	assert.True(t, instance.broken(errors.New("console error")))
+}
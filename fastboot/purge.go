@@ -0,0 +1,288 @@
+package fastboot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CacheTagsKey is the FastBoot metadata key under which an app may declare
+// the cache tags of a rendered page as a string or a list of strings.
+const CacheTagsKey = "cacheTags"
+
+// purgesKey is the reserved key under which purge markers are persisted in
+// caches that do not implement CacheScanner.
+const purgesKey = "\npurges"
+
+type purges struct {
+	All      time.Time            `json:"all"`
+	Keys     map[string]time.Time `json:"keys"`
+	Prefixes map[string]time.Time `json:"prefixes"`
+	Tags     map[string]time.Time `json:"tags"`
+}
+
+func (p *purges) init() {
+	// ensure maps
+	if p.Keys == nil {
+		p.Keys = map[string]time.Time{}
+	}
+	if p.Prefixes == nil {
+		p.Prefixes = map[string]time.Time{}
+	}
+	if p.Tags == nil {
+		p.Tags = map[string]time.Time{}
+	}
+}
+
+func (p *purges) prune(limit time.Time) {
+	// remove records that only match expired pages
+	for _, records := range []map[string]time.Time{p.Keys, p.Prefixes, p.Tags} {
+		for key, purged := range records {
+			if purged.Before(limit) {
+				delete(records, key)
+			}
+		}
+	}
+}
+
+func (p *purges) match(key string, created time.Time, tags []string) bool {
+	// check all
+	if !p.All.IsZero() && !created.After(p.All) {
+		return true
+	}
+
+	// check key
+	if purged, ok := p.Keys[key]; ok && !created.After(purged) {
+		return true
+	}
+
+	// check prefixes
+	for prefix, purged := range p.Prefixes {
+		if strings.HasPrefix(key, prefix) && !created.After(purged) {
+			return true
+		}
+	}
+
+	// check tags
+	for _, tag := range tags {
+		if purged, ok := p.Tags[tag]; ok && !created.After(purged) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Purge will invalidate the cached pages for the provided path including its
+// query string.
+func (h *Handler) Purge(path string) {
+	// check cache
+	if h.cache == nil {
+		return
+	}
+
+	// get cache key
+	r, err := http.NewRequest("GET", "/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return
+	}
+	key, ok := h.options.CacheKey(r)
+	if !ok {
+		return
+	}
+
+	// record purge
+	h.purge("key", func(p *purges, now time.Time) {
+		p.Keys[key] = now
+	})
+}
+
+// PurgePrefix will invalidate all cached pages whose cache key starts with
+// the provided prefix. Leading slashes are removed and the prefix is escaped
+// to match the keys returned by DefaultCacheKey.
+func (h *Handler) PurgePrefix(prefix string) {
+	prefix = (&url.URL{Path: strings.TrimLeft(prefix, "/")}).EscapedPath()
+	h.purge("prefix", func(p *purges, now time.Time) {
+		p.Prefixes[prefix] = now
+	})
+}
+
+// PurgeTag will invalidate all cached pages that declared the provided tag.
+func (h *Handler) PurgeTag(tag string) {
+	h.purge("tag", func(p *purges, now time.Time) {
+		p.Tags[tag] = now
+	})
+}
+
+// PurgeAll will invalidate all cached pages.
+func (h *Handler) PurgeAll() {
+	h.purge("all", func(p *purges, now time.Time) {
+		p.All = now
+	})
+}
+
+func (h *Handler) purge(kind string, record func(*purges, time.Time)) {
+	// check cache
+	if h.cache == nil {
+		return
+	}
+
+	// get time and retention (pages rendered before a purge may be stored
+	// until the render timeout and then live until the stale period ends)
+	now := time.Now()
+	retention := h.options.Cache + h.options.CacheStale + h.options.Timeout
+
+	// record local purge to skip pages that are still rendering
+	h.mutex.Lock()
+	h.purges.init()
+	record(&h.purges, now)
+	h.purges.prune(now.Add(-retention))
+	h.mutex.Unlock()
+
+	// acquire mutex
+	h.purging.Lock()
+	defer h.purging.Unlock()
+
+	// delete entries or persist purge
+	if scanner, ok := h.cache.(CacheScanner); ok {
+		var single purges
+		single.init()
+		record(&single, now)
+		h.deletePurged(scanner, &single)
+	} else {
+		stored := h.storedPurges()
+		stored.init()
+		record(&stored, now)
+		stored.prune(now.Add(-retention))
+		data, _ := json.Marshal(stored)
+		h.cache.Set(purgesKey, data, retention)
+	}
+
+	// count purge
+	count(h.options.Metrics, "fastboot_purges_total", kind)
+}
+
+func (h *Handler) deletePurged(scanner CacheScanner, p *purges) {
+	// check keys
+	for _, key := range scanner.Keys() {
+		// handle vary headers
+		base, _, variant := strings.Cut(key, "\n")
+		if !variant {
+			if p.match(key, time.Time{}, nil) {
+				h.cache.Delete(key)
+			}
+			continue
+		}
+
+		// skip reserved keys
+		if base == "" {
+			continue
+		}
+
+		// decode page
+		data, ok := h.cache.Get(key)
+		if !ok {
+			continue
+		}
+		pg, ok := decodePage(data)
+		if !ok {
+			continue
+		}
+
+		// delete variant
+		if p.match(base, pg.created, pg.tags) {
+			h.cache.Delete(key)
+		}
+	}
+}
+
+func (h *Handler) storedPurges() purges {
+	// get purges
+	var stored purges
+	data, ok := h.cache.Get(purgesKey)
+	if ok {
+		_ = json.Unmarshal(data, &stored)
+	}
+
+	return stored
+}
+
+func (h *Handler) purged(key string, pg *page) bool {
+	// check local purges
+	h.mutex.Lock()
+	purged := h.purges.match(key, pg.created, pg.tags)
+	h.mutex.Unlock()
+	if purged {
+		return true
+	}
+
+	// check stored purges
+	if _, ok := h.cache.(CacheScanner); !ok {
+		stored := h.storedPurges()
+		return stored.match(key, pg.created, pg.tags)
+	}
+
+	return false
+}
+
+func (h *Handler) servePurge(w http.ResponseWriter, r *http.Request) {
+	// check method
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// check token
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.options.PurgeToken)) != 1 {
+		http.Error(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// parse form
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	// perform purges
+	for _, path := range r.Form["path"] {
+		h.Purge(path)
+	}
+	for _, prefix := range r.Form["prefix"] {
+		h.PurgePrefix(prefix)
+	}
+	for _, tag := range r.Form["tag"] {
+		h.PurgeTag(tag)
+	}
+	if r.Form.Get("all") != "" {
+		h.PurgeAll()
+	}
+
+	// write response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func cacheTags(metadata map[string]interface{}) []string {
+	// get tags
+	switch value := metadata[CacheTagsKey].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var tags []string
+		for _, item := range value {
+			if tag, ok := item.(string); ok {
+				tags = append(tags, tag)
+			}
+		}
+		return tags
+	case []string:
+		return value
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package fastboot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember/example"
+)
+
+type plainCache struct {
+	Cache
+}
+
+func TestHandlerPurge(t *testing.T) {
+	for _, cache := range []Cache{
+		NewMemoryCache(DefaultCacheSize),
+		plainCache{NewMemoryCache(DefaultCacheSize)},
+	} {
+		handler := purgeHandler(cache)
+
+		store := func(path string, tags ...string) {
+			storePage(handler, path, time.Now(), tags...)
+		}
+
+		cached := func(path string) bool {
+			return cachedPage(handler, path)
+		}
+
+		store("/")
+		store("/blog/foo", "post:foo")
+		store("/blog/bar", "post:bar")
+		store("/about?lang=de")
+		assert.True(t, cached("/"))
+		assert.True(t, cached("/blog/foo"))
+		assert.True(t, cached("/blog/bar"))
+		assert.True(t, cached("/about?lang=de"))
+
+		handler.Purge("/about?lang=de")
+		assert.False(t, cached("/about?lang=de"))
+		assert.True(t, cached("/"))
+
+		handler.PurgeTag("post:foo")
+		assert.False(t, cached("/blog/foo"))
+		assert.True(t, cached("/blog/bar"))
+
+		time.Sleep(time.Millisecond)
+		store("/blog/foo", "post:foo")
+		assert.True(t, cached("/blog/foo"))
+
+		handler.PurgePrefix("/blog/")
+		assert.False(t, cached("/blog/foo"))
+		assert.False(t, cached("/blog/bar"))
+		assert.True(t, cached("/"))
+
+		handler.PurgeAll()
+		assert.False(t, cached("/"))
+
+		time.Sleep(time.Millisecond)
+		store("/")
+		assert.True(t, cached("/"))
+	}
+}
+
+func TestHandlerPurgeShared(t *testing.T) {
+	dir := t.TempDir()
+
+	fileCache := func() Cache {
+		cache, err := NewFileCache(dir)
+		assert.NoError(t, err)
+		return cache
+	}
+
+	memory := NewMemoryCache(DefaultCacheSize)
+
+	for _, caches := range [][2]Cache{
+		{fileCache(), fileCache()},
+		{plainCache{memory}, plainCache{memory}},
+	} {
+		first := purgeHandler(caches[0])
+		second := purgeHandler(caches[1])
+
+		storePage(first, "/blog/foo", time.Now(), "post:foo")
+		storePage(first, "/blog/bar", time.Now(), "post:bar")
+		storePage(first, "/about", time.Now())
+		assert.True(t, cachedPage(second, "/blog/foo"))
+		assert.True(t, cachedPage(second, "/blog/bar"))
+		assert.True(t, cachedPage(second, "/about"))
+
+		second.PurgeTag("post:foo")
+		assert.False(t, cachedPage(first, "/blog/foo"))
+		assert.True(t, cachedPage(first, "/blog/bar"))
+
+		second.PurgePrefix("/blog")
+		assert.False(t, cachedPage(first, "/blog/bar"))
+		assert.True(t, cachedPage(first, "/about"))
+
+		second.PurgeAll()
+		assert.False(t, cachedPage(first, "/about"))
+	}
+
+	restarted := purgeHandler(fileCache())
+	assert.False(t, cachedPage(restarted, "/about"))
+}
+
+func TestHandlerPurgeRendering(t *testing.T) {
+	handler := purgeHandler(NewMemoryCache(DefaultCacheSize))
+
+	created := time.Now()
+	handler.PurgeAll()
+
+	storePage(handler, "/", created)
+	assert.False(t, cachedPage(handler, "/"))
+
+	storePage(handler, "/", time.Now().Add(time.Millisecond))
+	assert.True(t, cachedPage(handler, "/"))
+}
+
+func purgeHandler(cache Cache) *Handler {
+	return &Handler{
+		options: Options{
+			Cache:    time.Minute,
+			CacheKey: DefaultCacheKey,
+			Timeout:  time.Second,
+		},
+		cache: cache,
+	}
+}
+
+func storePage(handler *Handler, path string, created time.Time, tags ...string) {
+	req := httptest.NewRequest("GET", path, nil)
+	key, _ := DefaultCacheKey(req)
+	handler.store(key, req, nil, &page{
+		headers: http.Header{},
+		body:    []byte(path),
+		created: created,
+		tags:    tags,
+	})
+}
+
+func cachedPage(handler *Handler, path string) bool {
+	req := httptest.NewRequest("GET", path, nil)
+	key, _ := DefaultCacheKey(req)
+	_, ok := handler.lookup(key, req)
+	return ok
+}
+
+func TestHandlerPurgeEndpoint(t *testing.T) {
+	_, err := Handle(Options{
+		App:       example.App(),
+		PurgePath: "/_purge",
+	})
+	assert.Error(t, err)
+
+	handler := &Handler{
+		options: Options{
+			Cache:      time.Minute,
+			CacheKey:   DefaultCacheKey,
+			PurgePath:  "/_purge",
+			PurgeToken: "secret",
+		},
+		cache: NewMemoryCache(DefaultCacheSize),
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/_purge", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/_purge?all=1", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.True(t, handler.purges.All.IsZero())
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/_purge", strings.NewReader("path=/foo&prefix=/blog&tag=post:1&all=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, handler.purges.All.IsZero())
+	assert.Contains(t, handler.purges.Keys, "foo")
+	assert.Contains(t, handler.purges.Prefixes, "blog")
+	assert.Contains(t, handler.purges.Tags, "post:1")
+}
+
+func TestCacheTags(t *testing.T) {
+	assert.Nil(t, cacheTags(nil))
+	assert.Equal(t, []string{"foo"}, cacheTags(map[string]interface{}{
+		"cacheTags": "foo",
+	}))
+	assert.Equal(t, []string{"foo", "bar"}, cacheTags(map[string]interface{}{
+		"cacheTags": []interface{}{"foo", 1, "bar"},
+	}))
+}
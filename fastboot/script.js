@@ -154,6 +154,9 @@
             // set flag
             window.$running = true;
 
+            // clear info
+            window.$info = null;
+
             // clear document
             document.head.innerHTML = '';
             document.body.innerHTML = '';
@@ -164,6 +167,10 @@
             // build instance
             window.$instance = await $app.buildInstance();
 
+            // extract metadata
+            const metadata = request.metadata || {};
+            delete request.metadata;
+
             // setup request
             request.headers = new FastBootHeaders(request.headers);
             request.host = () => request.headers.get('host');
@@ -175,7 +182,8 @@
                     headers: new FastBootHeaders({}),
                     statusCode: 200,
                 },
-                metadata: {},
+                metadata: metadata,
+                shoebox: {},
                 deferredPromise: Promise.resolve(),
                 deferRendering(promise) {
                     this.deferredPromise = promise;
@@ -200,6 +208,12 @@
             // wait for deferred promise
             await info.deferredPromise;
 
+            // get final URL
+            info.url = $instance.lookup('service:router').currentURL;
+
+            // keep info
+            window.$info = info;
+
             return info;
         } catch (err) {
             throw err;
@@ -216,6 +230,11 @@
             htmlAttributes: Object.fromEntries(Array.from(document.documentElement.attributes).map(a => [a.name, a.value])),
             headAttributes: Object.fromEntries(Array.from(document.head.attributes).map(a => [a.name, a.value])),
             bodyAttributes: Object.fromEntries(Array.from(document.body.attributes).map(a => [a.name, a.value])),
+            statusCode: window.$info ? window.$info.response.statusCode : 200,
+            headers: window.$info ? window.$info.response.headers.headers : {},
+            url: window.$info ? window.$info.url : '',
+            shoebox: window.$info ? window.$info.shoebox : {},
+            metadata: window.$info ? window.$info.metadata : {},
         };
     }
 })();
@@ -0,0 +1,139 @@
+package fastboot
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNoCache is returned if the cache is warmed while caching is disabled.
+var ErrNoCache = errors.New("cache disabled")
+
+// ErrHandlerClosed is returned if the handler has been closed.
+var ErrHandlerClosed = errors.New("handler closed")
+
+// Warm will render the provided URLs one after another and store the pages
+// in the render cache. Failed renders are reported through the OnWarm
+// callback and do not stop the warmup.
+func (h *Handler) Warm(ctx context.Context, urls []string) error {
+	// check cache
+	if h.cache == nil {
+		return ErrNoCache
+	}
+
+	// warm URLs
+	for i, u := range urls {
+		// check context
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		// warm URL
+		err = h.warm(u)
+		if err != nil {
+			count(h.options.Metrics, "fastboot_warm_total", "failure")
+		} else {
+			count(h.options.Metrics, "fastboot_warm_total", "success")
+		}
+
+		// report progress
+		if h.options.OnWarm != nil {
+			h.options.OnWarm(u, i+1, len(urls), err)
+		}
+
+		// stop if closed
+		if errors.Is(err, ErrHandlerClosed) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) warm(u string) error {
+	// prepare request
+	r, err := http.NewRequest("GET", strings.TrimRight(h.options.Origin, "/")+"/"+strings.TrimLeft(u, "/"), nil)
+	if err != nil {
+		return err
+	}
+
+	// forward protocol
+	if r.URL.Scheme != "" {
+		r.Header.Set("X-Forwarded-Proto", r.URL.Scheme)
+	}
+
+	// get cache key
+	key, ok := h.options.CacheKey(r)
+	if !ok {
+		return nil
+	}
+
+	// track render
+	if !h.acquire() {
+		return ErrHandlerClosed
+	}
+	defer h.active.Done()
+
+	// render page
+	pg, result, _, err := h.render(r)
+	if err != nil {
+		return err
+	}
+
+	// cache page if possible
+	if pg.headers.Get("Set-Cookie") == "" {
+		h.store(key, r, result.Headers, pg)
+	}
+
+	return nil
+}
+
+func warmURLs(options Options) ([]string, error) {
+	// get configured URLs
+	urls := append([]string{}, options.WarmURLs...)
+
+	// add sitemap URLs
+	if options.WarmSitemap != "" {
+		data := options.App.File(options.WarmSitemap)
+		if data == nil {
+			return nil, fmt.Errorf("missing sitemap %q", options.WarmSitemap)
+		}
+		list, err := parseSitemap(data)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, list...)
+	}
+
+	return urls, nil
+}
+
+func parseSitemap(data []byte) ([]string, error) {
+	// parse sitemap
+	var sitemap struct {
+		URLs []struct {
+			Loc string `xml:"loc"`
+		} `xml:"url"`
+	}
+	err := xml.Unmarshal(data, &sitemap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	// collect URLs
+	urls := make([]string, 0, len(sitemap.URLs))
+	for _, entry := range sitemap.URLs {
+		loc, err := url.Parse(strings.TrimSpace(entry.Loc))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sitemap URL %q: %w", entry.Loc, err)
+		}
+		urls = append(urls, loc.RequestURI())
+	}
+
+	return urls, nil
+}
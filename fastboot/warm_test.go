@@ -0,0 +1,105 @@
+package fastboot
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/ember/example"
+)
+
+func TestHandlerWarm(t *testing.T) {
+	app := example.App()
+
+	var renders int32
+	done := make(chan struct{})
+	var warmed []string
+	handler, err := Handle(Options{
+		App:      app,
+		Origin:   "https://example.org",
+		Cache:    time.Minute,
+		WarmURLs: []string{"/", "/status?code=404"},
+		OnResult: func(*Result) {
+			atomic.AddInt32(&renders, 1)
+		},
+		OnError: func(err error) {
+			assert.NoError(t, err)
+		},
+		OnWarm: func(url string, n, total int, err error) {
+			assert.NoError(t, err)
+			assert.Equal(t, 2, total)
+			warmed = append(warmed, url)
+			if n == total {
+				close(done)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * timeout):
+		t.Fatal("warmup timed out")
+	}
+	assert.Equal(t, []string{"/", "/status?code=404"}, warmed)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&renders))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "https://example.org/status?code=404", nil))
+	assert.Equal(t, 404, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<p>Status: 404</p>`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&renders))
+}
+
+func TestHandlerWarmErrors(t *testing.T) {
+	handler := &Handler{}
+	err := handler.Warm(context.Background(), []string{"/"})
+	assert.Equal(t, ErrNoCache, err)
+
+	var progress []string
+	handler = &Handler{
+		options: Options{
+			CacheKey: DefaultCacheKey,
+			OnWarm: func(url string, done, total int, err error) {
+				progress = append(progress, url)
+				assert.Equal(t, ErrHandlerClosed, err)
+			},
+		},
+		cache:  NewMemoryCache(DefaultCacheSize),
+		closed: true,
+	}
+	err = handler.Warm(context.Background(), []string{"/", "/foo"})
+	assert.Equal(t, ErrHandlerClosed, err)
+	assert.Equal(t, []string{"/"}, progress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = handler.Warm(ctx, []string{"/"})
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = Handle(Options{
+		App:         example.App(),
+		Cache:       time.Minute,
+		WarmSitemap: "sitemap.xml",
+	})
+	assert.Error(t, err)
+}
+
+func TestParseSitemap(t *testing.T) {
+	urls, err := parseSitemap([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+		<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+			<url><loc>https://example.org/</loc></url>
+			<url><loc> https://example.org/foo?bar=baz </loc></url>
+			<url><loc>https://example.org/status</loc><lastmod>2024-01-01</lastmod></url>
+		</urlset>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/", "/foo?bar=baz", "/status"}, urls)
+
+	_, err = parseSitemap([]byte("<urlset"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package ember
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccessWriter wraps a http.ResponseWriter to capture the response status and
+// the number of written bytes.
+type AccessWriter struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int64
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *AccessWriter) WriteHeader(status int) {
+	if w.Status == 0 {
+		w.Status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *AccessWriter) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += int64(n)
+	return n, err
+}
+
+// Flush implements the http.Flusher interface.
+func (w *AccessWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *AccessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// LogAccess will log the served request using the provided logger. The kind
+// describes how the request was answered e.g. "asset", "index", "cached",
+// "rendered" or "fallback". Requests that failed with an error are logged
+// with the warning level.
+func LogAccess(logger *slog.Logger, r *http.Request, w *AccessWriter, kind string, start time.Time, err error) {
+	// get status
+	status := w.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// prepare attributes
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("proto", r.Proto),
+		slog.Int("status", status),
+		slog.Int64("bytes", w.Bytes),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("kind", kind),
+		slog.String("remote", r.RemoteAddr),
+		slog.String("referer", r.Referer()),
+		slog.String("user_agent", r.UserAgent()),
+	}
+
+	// determine level
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	// log access
+	logger.LogAttrs(r.Context(), level, "request", attrs...)
+}
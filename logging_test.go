@@ -0,0 +1,82 @@
+package ember
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	app := MustCreate("app", files)
+	app.WithLogger(logger)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/script.js", nil))
+
+	rec = httptest.NewRecorder()
+	app.Clone().ServeHTTP(rec, httptest.NewRequest("GET", "/foo", nil))
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("POST", "/foo", nil))
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		err := json.Unmarshal([]byte(line), &entry)
+		assert.NoError(t, err)
+		delete(entry, "time")
+		assert.NotZero(t, entry["duration"])
+		delete(entry, "duration")
+		entries = append(entries, entry)
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"level":      "INFO",
+			"msg":        "request",
+			"method":     "GET",
+			"path":       "/script.js",
+			"proto":      "HTTP/1.1",
+			"status":     200.0,
+			"bytes":      22.0,
+			"kind":       "asset",
+			"remote":     "192.0.2.1:1234",
+			"referer":    "",
+			"user_agent": "",
+		},
+		{
+			"level":      "INFO",
+			"msg":        "request",
+			"method":     "GET",
+			"path":       "/foo",
+			"proto":      "HTTP/1.1",
+			"status":     200.0,
+			"bytes":      float64(len(indexHTML)),
+			"kind":       "index",
+			"remote":     "192.0.2.1:1234",
+			"referer":    "",
+			"user_agent": "",
+		},
+		{
+			"level":      "INFO",
+			"msg":        "request",
+			"method":     "POST",
+			"path":       "/foo",
+			"proto":      "HTTP/1.1",
+			"status":     405.0,
+			"bytes":      1.0,
+			"kind":       "invalid",
+			"remote":     "192.0.2.1:1234",
+			"referer":    "",
+			"user_agent": "",
+		},
+	}, entries)
+}
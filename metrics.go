@@ -0,0 +1,195 @@
+package ember
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsBuckets are the upper bounds in seconds of the histogram buckets
+// maintained by a Collector.
+var MetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is used to collect runtime metrics.
+type Metrics interface {
+	// Count will increment the named counter for the provided outcome.
+	Count(name, outcome string)
+
+	// Observe will record the provided duration in the named histogram for
+	// the provided outcome.
+	Observe(name, outcome string, duration time.Duration)
+}
+
+type metricKey struct {
+	name    string
+	outcome string
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Collector is an in-memory Metrics implementation that can be published
+// using expvar or served in the Prometheus text format.
+type Collector struct {
+	counters   map[metricKey]uint64
+	histograms map[metricKey]*histogram
+	mutex      sync.Mutex
+}
+
+// NewCollector will create and return a new collector.
+func NewCollector() *Collector {
+	return &Collector{
+		counters:   map[metricKey]uint64{},
+		histograms: map[metricKey]*histogram{},
+	}
+}
+
+// Count implements the Metrics interface.
+func (c *Collector) Count(name, outcome string) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// increment counter
+	c.counters[metricKey{name, outcome}]++
+}
+
+// Observe implements the Metrics interface.
+func (c *Collector) Observe(name, outcome string, duration time.Duration) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// get histogram
+	key := metricKey{name, outcome}
+	hist, ok := c.histograms[key]
+	if !ok {
+		hist = &histogram{
+			buckets: make([]uint64, len(MetricsBuckets)),
+		}
+		c.histograms[key] = hist
+	}
+
+	// update histogram
+	seconds := duration.Seconds()
+	for i, bound := range MetricsBuckets {
+		if seconds <= bound {
+			hist.buckets[i]++
+		}
+	}
+	hist.count++
+	hist.sum += seconds
+}
+
+// Snapshot will return a nested map of all collected metrics keyed by metric
+// name and outcome. Counters are returned as numbers and histograms as maps
+// with the "count", "sum" and "buckets" keys.
+func (c *Collector) Snapshot() map[string]map[string]interface{} {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// prepare snapshot
+	snapshot := map[string]map[string]interface{}{}
+	get := func(name string) map[string]interface{} {
+		if snapshot[name] == nil {
+			snapshot[name] = map[string]interface{}{}
+		}
+		return snapshot[name]
+	}
+
+	// add counters
+	for key, value := range c.counters {
+		get(key.name)[key.outcome] = value
+	}
+
+	// add histograms
+	for key, hist := range c.histograms {
+		buckets := map[string]uint64{}
+		for i, bound := range MetricsBuckets {
+			buckets[formatFloat(bound)] = hist.buckets[i]
+		}
+		get(key.name)[key.outcome] = map[string]interface{}{
+			"count":   hist.count,
+			"sum":     hist.sum,
+			"buckets": buckets,
+		}
+	}
+
+	return snapshot
+}
+
+// Publish will publish the collected metrics under the provided name using
+// the expvar package.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Snapshot()
+	}))
+}
+
+// ServeHTTP implements the http.Handler interface and writes the collected
+// metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// set content type
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	// collect keys
+	var counters, histograms []metricKey
+	for key := range c.counters {
+		counters = append(counters, key)
+	}
+	for key := range c.histograms {
+		histograms = append(histograms, key)
+	}
+
+	// write counters
+	var last string
+	for _, key := range sortedKeys(counters) {
+		if key.name != last {
+			_, _ = fmt.Fprintf(w, "# TYPE %s counter\n", key.name)
+			last = key.name
+		}
+		_, _ = fmt.Fprintf(w, "%s{outcome=%q} %d\n", key.name, key.outcome, c.counters[key])
+	}
+
+	// write histograms
+	last = ""
+	for _, key := range sortedKeys(histograms) {
+		if key.name != last {
+			_, _ = fmt.Fprintf(w, "# TYPE %s histogram\n", key.name)
+			last = key.name
+		}
+		hist := c.histograms[key]
+		for i, bound := range MetricsBuckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket{outcome=%q,le=\"%s\"} %d\n", key.name, key.outcome, formatFloat(bound), hist.buckets[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket{outcome=%q,le=\"+Inf\"} %d\n", key.name, key.outcome, hist.count)
+		_, _ = fmt.Fprintf(w, "%s_sum{outcome=%q} %s\n", key.name, key.outcome, formatFloat(hist.sum))
+		_, _ = fmt.Fprintf(w, "%s_count{outcome=%q} %d\n", key.name, key.outcome, hist.count)
+	}
+}
+
+func sortedKeys(keys []metricKey) []metricKey {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].outcome < keys[j].outcome
+	})
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
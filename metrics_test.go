@@ -0,0 +1,50 @@
+package ember
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector()
+
+	app := MustCreate("app", files)
+	app.WithMetrics(collector)
+
+	for _, pth := range []string{"/", "/foo", "/script.js"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", pth, nil))
+	}
+
+	handler := app.Handler(func(*App, *http.Request) {})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bar", nil))
+
+	snapshot := collector.Snapshot()
+	assert.Equal(t, map[string]interface{}{
+		"asset": uint64(1),
+		"index": uint64(3),
+	}, snapshot["ember_app_requests_total"])
+	assert.Equal(t, map[string]interface{}{
+		"success": uint64(1),
+	}, snapshot["ember_app_configure_total"])
+	assert.Len(t, snapshot["ember_app_requests_seconds"], 2)
+
+	rec = httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, "# TYPE ember_app_configure_total counter\n"))
+	assert.Contains(t, body, "ember_app_requests_total{outcome=\"index\"} 3\n")
+	assert.Contains(t, body, "# TYPE ember_app_requests_seconds histogram\n")
+	assert.Contains(t, body, "ember_app_requests_seconds_bucket{outcome=\"asset\",le=\"+Inf\"} 1\n")
+	assert.Contains(t, body, "ember_app_requests_seconds_count{outcome=\"index\"} 3\n")
+
+	collector.Publish("ember-test")
+	assert.NotNil(t, expvar.Get("ember-test"))
+	assert.Contains(t, expvar.Get("ember-test").String(), "ember_app_requests_total")
+}
@@ -0,0 +1,207 @@
+package ember
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"regexp"
+	"strings"
+)
+
+// The available problem kinds.
+const (
+	MissingAsset        = "missing-asset"
+	IntegrityMismatch   = "integrity-mismatch"
+	MissingManifestFile = "missing-manifest-file"
+	InvalidManifest     = "invalid-manifest"
+	PrefixMismatch      = "prefix-mismatch"
+)
+
+var tagPattern = regexp.MustCompile(`<(script|link)\s[^>]*>`)
+var attrPattern = regexp.MustCompile(`\s(src|href|integrity|rel)="([^"]*)"`)
+
+var assetRels = map[string]bool{
+	"stylesheet":    true,
+	"preload":       true,
+	"modulepreload": true,
+	"manifest":      true,
+}
+
+var integrityHashes = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha384", sha512.New384},
+	{"sha256", sha256.New},
+}
+
+// Problem describes an issue found while verifying an application.
+type Problem struct {
+	Kind    string `json:"kind"`
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+// String will return a human-readable description of the problem.
+func (p Problem) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s: %s (%s)", p.Kind, p.Message, p.File)
+	}
+	return fmt.Sprintf("%s: %s", p.Kind, p.Message)
+}
+
+// Verify will check the application build for common problems. It reports
+// assets referenced by the index that are missing, subresource integrity
+// mismatches, FastBoot manifest files that do not exist and a configured
+// module prefix that differs from the application name.
+func (a *App) Verify() []Problem {
+	// prepare list
+	var problems []Problem
+
+	// get root URL
+	rootURL, _ := a.Get("rootURL").(string)
+	if rootURL == "" {
+		rootURL = "/"
+	}
+
+	// get files of the current build (previous builds are ignored)
+	files := a.getFiles()
+
+	// check index references
+	for _, tag := range tagPattern.FindAllSubmatch(a.File(indexHTMLFile), -1) {
+		// get attributes
+		attrs := map[string]string{}
+		for _, match := range attrPattern.FindAllSubmatch(tag[0], -1) {
+			attrs[string(match[1])] = string(match[2])
+		}
+
+		// get reference (links are only assets if they load a resource)
+		ref, integrity := attrs["src"], attrs["integrity"]
+		if string(tag[1]) == "link" {
+			ref = ""
+			if integrity != "" || isAssetLink(attrs["rel"]) {
+				ref = attrs["href"]
+			}
+		}
+
+		// skip external and relative references
+		if !strings.HasPrefix(ref, rootURL) || strings.HasPrefix(ref, "//") {
+			continue
+		}
+
+		// get file
+		name := strings.SplitN(strings.TrimPrefix(ref, rootURL), "?", 2)[0]
+		if name == "" {
+			continue
+		}
+		content, ok := files[name]
+		if !ok {
+			problems = append(problems, Problem{
+				Kind:    MissingAsset,
+				File:    name,
+				Message: "referenced asset does not exist",
+			})
+			continue
+		}
+
+		// check integrity
+		if !checkIntegrity(integrity, content) {
+			problems = append(problems, Problem{
+				Kind:    IntegrityMismatch,
+				File:    name,
+				Message: "asset does not match integrity attribute",
+			})
+		}
+	}
+
+	// check FastBoot manifest
+	if packageJSON := files["package.json"]; packageJSON != nil {
+		var pkg struct {
+			Fastboot struct {
+				Manifest struct {
+					AppFiles    []string `json:"appFiles"`
+					VendorFiles []string `json:"vendorFiles"`
+					HTMLFile    string   `json:"htmlFile"`
+				} `json:"manifest"`
+			} `json:"fastboot"`
+		}
+		err := json.Unmarshal(packageJSON, &pkg)
+		if err != nil {
+			problems = append(problems, Problem{
+				Kind:    InvalidManifest,
+				File:    "package.json",
+				Message: "failed to parse package.json: " + err.Error(),
+			})
+		}
+		manifest := pkg.Fastboot.Manifest
+		for _, name := range append(append([]string{manifest.HTMLFile}, manifest.VendorFiles...), manifest.AppFiles...) {
+			if _, ok := files[name]; name != "" && !ok {
+				problems = append(problems, Problem{
+					Kind:    MissingManifestFile,
+					File:    name,
+					Message: "FastBoot manifest file does not exist",
+				})
+			}
+		}
+	}
+
+	// check module prefix
+	if prefix, ok := a.Get("modulePrefix").(string); ok && prefix != a.name {
+		problems = append(problems, Problem{
+			Kind:    PrefixMismatch,
+			Message: fmt.Sprintf("module prefix %q does not match app name %q", prefix, a.name),
+		})
+	}
+
+	return problems
+}
+
+func isAssetLink(rel string) bool {
+	// check types
+	for _, typ := range strings.Fields(strings.ToLower(rel)) {
+		if assetRels[typ] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkIntegrity(integrity string, content []byte) bool {
+	// collect hashes
+	hashes := map[string][]string{}
+	for _, token := range strings.Fields(integrity) {
+		alg, value, ok := strings.Cut(token, "-")
+		if ok {
+			hashes[alg] = append(hashes[alg], strings.SplitN(value, "?", 2)[0])
+		}
+	}
+
+	// check strongest algorithm
+	for _, alg := range integrityHashes {
+		values := hashes[alg.name]
+		if len(values) == 0 {
+			continue
+		}
+
+		// compute digest
+		h := alg.new()
+		h.Write(content)
+		digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		// match values
+		for _, value := range values {
+			if value == digest {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
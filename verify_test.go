@@ -0,0 +1,55 @@
+package ember
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppVerify(t *testing.T) {
+	app := MustCreate("app", map[string]string{
+		"index.html": indexHTML,
+		"assets/vendor-d41d8cd98f00b204e9800998ecf8427e.css": "",
+		"assets/vendor-0602240bb8c898070836851c4cc335bd.js":  "invalid",
+		"package.json": `{"fastboot":{"manifest":{"appFiles":["assets/app-fastboot.js"],"vendorFiles":["assets/vendor-0602240bb8c898070836851c4cc335bd.js"]}}}`,
+	})
+	assert.Equal(t, []Problem{
+		{Kind: MissingAsset, File: "assets/app-45c749a3bbece8e3ce4ffd9e6b8addf7.css", Message: "referenced asset does not exist"},
+		{Kind: IntegrityMismatch, File: "assets/vendor-0602240bb8c898070836851c4cc335bd.js", Message: "asset does not match integrity attribute"},
+		{Kind: MissingAsset, File: "assets/app-6a49fc3c244bed354719f50d3ca3dd38.js", Message: "referenced asset does not exist"},
+		{Kind: MissingManifestFile, File: "assets/app-fastboot.js", Message: "FastBoot manifest file does not exist"},
+	}, app.Verify())
+
+	app = MustCreate("foo", map[string]string{
+		"index.html": `<html><head>
+			<meta name="foo/config/environment" content="%7B%22modulePrefix%22%3A%22app%22%7D"/>
+			<link rel="canonical" href="/">
+			<link rel="canonical" href="/pricing">
+			<link rel="alternate" hreflang="de" href="/de/pricing">
+			<link rel="preconnect" href="/api">
+			<link rel="preload" as="script" href="/assets/app.js">
+			</head><body>
+			<script src="/assets/app.js" integrity="sha256-LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564="></script>
+			<script src="https://cdn.example.com/lib.js"></script>
+			</body></html>`,
+		"assets/app.js": "foo",
+	})
+	assert.Equal(t, []Problem{
+		{Kind: PrefixMismatch, Message: `module prefix "app" does not match app name "foo"`},
+	}, app.Verify())
+	assert.Equal(t, `prefix-mismatch: module prefix "app" does not match app name "foo"`, app.Verify()[0].String())
+
+	old := MustCreate("app", map[string]string{
+		"index.html": indexHTML,
+		"assets/app-45c749a3bbece8e3ce4ffd9e6b8addf7.css": "",
+	})
+	app = MustCreate("app", map[string]string{
+		"index.html": indexHTML,
+	})
+	app.WithPrevious(old, 0)
+	assert.Contains(t, app.Verify(), Problem{
+		Kind:    MissingAsset,
+		File:    "assets/app-45c749a3bbece8e3ce4ffd9e6b8addf7.css",
+		Message: "referenced asset does not exist",
+	})
+}